@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/taldoflemis/box-box/pacchetto"
+	"github.com/taldoflemis/box-box/pacchetto/telemetry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	statusWaitingToCook  = "waiting_to_cook"
+	statusPaymentFailed  = "payment_failed"
+	paymentOutcomePaid   = "paid"
+	paymentOutcomeFree   = "free"
+	paymentOutcomeFailed = "failed"
+)
+
+type Order = pacchetto.Order
+
+type caixaHandler struct {
+	settings        CaixaSettings
+	provider        PaymentProvider
+	subject         string
+	consumer        jetstream.Consumer
+	stream          jetstream.Stream
+	statuses        *pacchetto.OrderStatusPublisher
+	paymentCounter  metric.Int64Counter
+	paymentDuration metric.Float64Histogram
+}
+
+var (
+	tracer = otel.Tracer("caixa")
+	meter  = otel.Meter("caixa")
+)
+
+func newCaixaHandler(settings CaixaSettings,
+	provider PaymentProvider,
+	nc *nats.Conn,
+	streamName string,
+	subject string,
+	statusBucket string,
+) (*caixaHandler, error) {
+	ctx := context.Background()
+
+	paymentCounter, err := meter.Int64Counter(
+		"caixa.payment.count",
+		metric.WithDescription("Number of orders the caixa has charged, by outcome"),
+		metric.WithUnit("{payment}"),
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to create payment counter", slog.Any("err", err))
+		return nil, err
+	}
+
+	paymentDuration, err := meter.Float64Histogram(
+		"caixa.payment.duration",
+		metric.WithDescription("Duration of the charges made to the payment provider"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to create payment histogram", slog.Any("err", err))
+		return nil, err
+	}
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to create jetstream context", slog.Any("err", err))
+		return nil, err
+	}
+
+	stream, err := js.Stream(ctx, streamName)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to get stream", slog.Any("err", err))
+		return nil, err
+	}
+
+	statusKV, err := pacchetto.CreateOrderStatusBucket(ctx, js, statusBucket)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to create order status bucket", slog.Any("err", err))
+		return nil, err
+	}
+
+	c, err := stream.CreateOrUpdateConsumer(ctx, jetstream.ConsumerConfig{
+		Durable:       streamName + "_caixa_payment_listener_v1",
+		FilterSubject: fmt.Sprintf("%s.waiting_payment.*", subject),
+		AckPolicy:     jetstream.AckExplicitPolicy,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to create consumer", slog.Any("err", err))
+		return nil, err
+	}
+
+	return &caixaHandler{
+		settings:        settings,
+		provider:        provider,
+		subject:         subject,
+		consumer:        c,
+		stream:          stream,
+		statuses:        pacchetto.NewOrderStatusPublisher(js, statusKV, subject),
+		paymentCounter:  paymentCounter,
+		paymentDuration: paymentDuration,
+	}, nil
+}
+
+func (h *caixaHandler) startShift(ctx context.Context) {
+	slog.InfoContext(ctx, "Caixa is opening the till")
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "Caixa closed the till")
+			return
+		default:
+			msgs, err := h.consumer.Fetch(h.settings.OrderBatchSize,
+				jetstream.FetchMaxWait(time.Duration(h.settings.FetchMaxWaitInSeconds)*time.Second),
+			)
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to consume messages", slog.Any("err", err))
+				continue
+			}
+
+			for msg := range msgs.Messages() {
+				h.processPayment(context.Background(), msg)
+			}
+		}
+	}
+}
+
+func (h *caixaHandler) processPayment(ctx context.Context, msg jetstream.Msg) {
+	ctx = telemetry.GetContextFromJetstreamMsg(ctx, msg)
+	ctx, span := tracer.Start(ctx, "caixaHandler.processPayment")
+	defer span.End()
+
+	var order Order
+	err := json.Unmarshal(msg.Data(), &order)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to unmarshal order from NATS message", slog.Any("err", err))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		h.term(ctx, msg)
+		return
+	}
+
+	span.SetAttributes(
+		attribute.String("box-box.orderid", order.OrderID),
+		attribute.String("order.username", order.Username),
+		attribute.Int64("order.total", order.Total),
+		attribute.String("order.currency", order.Currency),
+	)
+
+	cancelled, err := h.isCancelled(ctx, order.OrderID)
+	if err != nil {
+		slog.WarnContext(ctx, "Could not check the order for a cancellation, retrying later", slog.String("order-id", order.OrderID))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "failed to look up order cancellation")
+		h.nak(ctx, msg)
+		return
+	}
+
+	if cancelled {
+		slog.InfoContext(ctx, "Order was cancelled before payment, dropping it", slog.String("order-id", order.OrderID))
+		span.AddEvent("order cancelled before payment")
+		h.term(ctx, msg)
+		return
+	}
+
+	outcome := paymentOutcomePaid
+	if order.Total == 0 {
+		// Orders placed without prices, or fully discounted, have nothing to charge
+		outcome = paymentOutcomeFree
+	} else {
+		payment, err := h.charge(ctx, msg, order)
+		switch {
+		case err == nil:
+			order.PaymentID = payment.ID
+		case errors.Is(err, ErrPaymentDeclined):
+			outcome = paymentOutcomeFailed
+			order.Reason = err.Error()
+		default:
+			attempts := h.attempts(ctx, msg)
+			if attempts < h.settings.MaxAttempts {
+				slog.WarnContext(ctx, "Payment provider failed, retrying later", slog.String("order-id", order.OrderID), slog.Int("attempt", attempts), slog.Any("err", err))
+				h.nak(ctx, msg)
+				return
+			}
+
+			outcome = paymentOutcomeFailed
+			order.Reason = fmt.Sprintf("payment failed after %d attempts: %s", attempts, err)
+		}
+	}
+
+	h.paymentCounter.Add(ctx, 1, metric.WithAttributes(attribute.String("caixa.outcome", outcome)))
+	span.SetAttributes(attribute.String("caixa.outcome", outcome))
+
+	if outcome == paymentOutcomeFailed {
+		slog.InfoContext(ctx, "Payment failed", slog.String("order-id", order.OrderID), slog.String("reason", order.Reason))
+		err = h.statuses.Publish(ctx, order, statusPaymentFailed)
+	} else {
+		slog.InfoContext(ctx, "Order paid, sending it to the kitchen", slog.String("order-id", order.OrderID), slog.String("payment-id", order.PaymentID))
+		err = h.statuses.Publish(ctx, order, statusWaitingToCook)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to publish order status", slog.String("order-id", order.OrderID), slog.Any("err", err))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "failed to publish order status")
+		// Charging again is safe, the provider returns the first payment
+		h.nak(ctx, msg)
+		return
+	}
+
+	err = msg.Ack()
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to acknowledge message", slog.Any("err", err))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// charge asks the provider for the order total, giving up after the charge
+// timeout. msg is kept in progress meanwhile so a slow provider does not get
+// the order redelivered to another replica.
+func (h *caixaHandler) charge(ctx context.Context, msg jetstream.Msg, order Order) (Payment, error) {
+	ctx, span := tracer.Start(ctx, "caixaHandler.charge", trace.WithAttributes(
+		attribute.String("box-box.orderid", order.OrderID),
+	))
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(h.settings.ChargeTimeoutInSeconds)*time.Second)
+	defer cancel()
+
+	go h.keepInProgress(ctx, msg)
+
+	start := time.Now()
+	payment, err := h.provider.Charge(ctx, PaymentRequest{
+		IdempotencyKey: order.OrderID,
+		OrderID:        order.OrderID,
+		Username:       order.Username,
+		Amount:         order.Total,
+		Currency:       order.Currency,
+	})
+	h.paymentDuration.Record(ctx, time.Since(start).Seconds())
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "failed to charge order")
+		return Payment{}, err
+	}
+
+	return payment, nil
+}
+
+// attempts is how often the message was delivered, including this delivery.
+func (h *caixaHandler) attempts(ctx context.Context, msg jetstream.Msg) int {
+	meta, err := msg.Metadata()
+	if err != nil {
+		slog.WarnContext(ctx, "failed to read message metadata", slog.Any("err", err))
+		return 1
+	}
+	return int(meta.NumDelivered)
+}
+
+// keepInProgress extends the ack deadline of msg every InProgressIntervalInSeconds
+// until ctx is done.
+func (h *caixaHandler) keepInProgress(ctx context.Context, msg jetstream.Msg) {
+	ticker := time.NewTicker(time.Duration(h.settings.InProgressIntervalInSeconds) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := msg.InProgress()
+			if err != nil {
+				slog.WarnContext(ctx, "failed to set message in progress", slog.Any("err", err))
+			}
+		}
+	}
+}
+
+func (h *caixaHandler) nak(ctx context.Context, msg jetstream.Msg) {
+	err := msg.NakWithDelay(time.Duration(h.settings.RetryDelayInSeconds) * time.Second)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to nak message", slog.Any("err", err))
+		trace.SpanFromContext(ctx).RecordError(err)
+	}
+}
+
+func (h *caixaHandler) term(ctx context.Context, msg jetstream.Msg) {
+	err := msg.Term()
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to terminate message", slog.Any("err", err))
+		trace.SpanFromContext(ctx).RecordError(err)
+	}
+}
+
+// isCancelled reports whether the customer published a cancellation event for the order.
+func (h *caixaHandler) isCancelled(ctx context.Context, orderID string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "caixaHandler.isCancelled", trace.WithAttributes(
+		attribute.String("box-box.orderid", orderID),
+	))
+	defer span.End()
+
+	_, err := h.stream.GetLastMsgForSubject(ctx, fmt.Sprintf("%s.cancelled.%s", h.subject, orderID))
+	if errors.Is(err, jetstream.ErrMsgNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to look up order cancellation", slog.String("order-id", orderID), slog.Any("err", err))
+		span.SetStatus(codes.Error, "failed to look up order cancellation")
+		span.RecordError(err)
+		return false, err
+	}
+
+	return true, nil
+}
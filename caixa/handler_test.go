@@ -0,0 +1,44 @@
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMsg counts the heartbeats sent for a message.
+type fakeMsg struct {
+	jetstream.Msg
+	inProgress atomic.Int32
+}
+
+func (m *fakeMsg) InProgress() error {
+	m.inProgress.Add(1)
+	return nil
+}
+
+func TestCharge(t *testing.T) {
+	// Arrange
+	h := &caixaHandler{
+		settings: CaixaSettings{ChargeTimeoutInSeconds: 5, InProgressIntervalInSeconds: 1},
+		provider: NewFakePaymentProvider(FakeProviderSettings{LatencyInMilliseconds: 2500, RememberedPayments: 1}),
+	}
+	var err error
+	h.paymentDuration, err = meter.Float64Histogram("caixa.payment.duration")
+	require.NoError(t, err)
+	msg := &fakeMsg{}
+	order := Order{OrderID: "123", Total: 4550, Currency: "EUR"}
+
+	// Act
+	payment, err := h.charge(t.Context(), msg, order)
+	time.Sleep(1500 * time.Millisecond)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, int64(4550), payment.Amount)
+	assert.Equal(t, int32(2), msg.inProgress.Load(), "kept in progress while charging, and only then")
+}
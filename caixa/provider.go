@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/taldoflemis/box-box/pacchetto"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrPaymentDeclined is returned when the customer cannot pay, charging again
+// will not help.
+var ErrPaymentDeclined = errors.New("payment declined")
+
+// PaymentRequest asks for Amount minor units of Currency to be charged for an
+// order.
+type PaymentRequest struct {
+	// IdempotencyKey identifies the charge at the provider, caixa uses the
+	// order ID so every retry of an order is the same charge
+	IdempotencyKey string
+	OrderID        string
+	Username       string
+	Amount         int64
+	Currency       string
+}
+
+type Payment struct {
+	ID       string
+	Amount   int64
+	Currency string
+	PaidAt   time.Time
+}
+
+// PaymentProvider charges customers. Errors other than ErrPaymentDeclined are
+// provider failures and the charge may be retried.
+//
+// Charge must be idempotent per IdempotencyKey: an order is charged again
+// when its message is redelivered, after a provider failure or when its new
+// status could not be published, and possibly by another replica. A repeated
+// key must return the first payment instead of charging the customer twice.
+type PaymentProvider interface {
+	Charge(ctx context.Context, req PaymentRequest) (Payment, error)
+}
+
+// FakePaymentProvider accepts every card after some latency, except for the
+// charges it randomly delays, declines or fails as configured. It remembers
+// the last RememberedPayments payments to answer repeated keys.
+type FakePaymentProvider struct {
+	settings FakeProviderSettings
+	// random reports true with the given probability, replaced in tests
+	random func(probability float64) bool
+
+	mu       sync.Mutex
+	payments map[string]Payment
+	// keys is a ring of the remembered keys, next is the oldest one
+	keys []string
+	next int
+}
+
+var _ PaymentProvider = (*FakePaymentProvider)(nil)
+
+func NewFakePaymentProvider(settings FakeProviderSettings) *FakePaymentProvider {
+	return &FakePaymentProvider{
+		settings: settings,
+		random: func(probability float64) bool {
+			return pacchetto.RandomFunction(uint64(time.Now().UnixNano()), probability)
+		},
+		payments: make(map[string]Payment, settings.RememberedPayments),
+		keys:     make([]string, settings.RememberedPayments),
+	}
+}
+
+// Charge implements PaymentProvider.
+func (f *FakePaymentProvider) Charge(ctx context.Context, req PaymentRequest) (Payment, error) {
+	ctx, span := tracer.Start(ctx, "FakePaymentProvider.Charge", trace.WithAttributes(
+		attribute.String("box-box.orderid", req.OrderID),
+		attribute.Int64("payment.amount", req.Amount),
+		attribute.String("payment.currency", req.Currency),
+	))
+	defer span.End()
+
+	payment, ok := f.payment(req.IdempotencyKey)
+	if ok {
+		slog.DebugContext(ctx, "Order was already charged", slog.String("order-id", req.OrderID))
+		return payment, nil
+	}
+
+	latency := time.Duration(f.settings.LatencyInMilliseconds) * time.Millisecond
+	if f.random(f.settings.ProbabilityOfDelay) {
+		latency += time.Duration(f.settings.DelayInMilliseconds) * time.Millisecond
+		span.SetAttributes(attribute.Bool("caixa.delayed", true))
+	}
+
+	select {
+	case <-ctx.Done():
+		return Payment{}, ctx.Err()
+	case <-time.After(latency):
+	}
+
+	if f.random(f.settings.ProbabilityOfError) {
+		return Payment{}, errors.New("payment provider unavailable")
+	}
+
+	if f.random(f.settings.ProbabilityOfDecline) {
+		return Payment{}, fmt.Errorf("%w: insufficient funds", ErrPaymentDeclined)
+	}
+
+	payment = Payment{
+		ID:       uuid.New().String(),
+		Amount:   req.Amount,
+		Currency: req.Currency,
+		PaidAt:   time.Now(),
+	}
+
+	return f.remember(req.IdempotencyKey, payment), nil
+}
+
+func (f *FakePaymentProvider) payment(key string) (Payment, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	payment, ok := f.payments[key]
+	return payment, ok
+}
+
+// remember keeps payment under key, forgetting the oldest payment when full.
+// A concurrent charge with the same key may have finished first, its payment
+// wins so both callers see the same one.
+func (f *FakePaymentProvider) remember(key string, payment Payment) Payment {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if first, ok := f.payments[key]; ok {
+		return first
+	}
+	if len(f.keys) == 0 {
+		return payment
+	}
+
+	if oldest := f.keys[f.next]; oldest != "" {
+		delete(f.payments, oldest)
+	}
+	f.keys[f.next] = key
+	f.next = (f.next + 1) % len(f.keys)
+	f.payments[key] = payment
+
+	return payment
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakePaymentProviderCharge(t *testing.T) {
+	tests := []struct {
+		name        string
+		settings    FakeProviderSettings
+		wantErr     bool
+		wantDecline bool
+	}{
+		{
+			name:     "accepted",
+			settings: FakeProviderSettings{RememberedPayments: 1},
+		},
+		{
+			name:        "declined",
+			settings:    FakeProviderSettings{ProbabilityOfDecline: 1, RememberedPayments: 1},
+			wantErr:     true,
+			wantDecline: true,
+		},
+		{
+			name:     "provider error",
+			settings: FakeProviderSettings{ProbabilityOfError: 1, RememberedPayments: 1},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			provider := NewFakePaymentProvider(tt.settings)
+			req := PaymentRequest{IdempotencyKey: "123", OrderID: "123", Username: "charles_leclerc", Amount: 4550, Currency: "EUR"}
+
+			// Act
+			payment, err := provider.Charge(t.Context(), req)
+
+			// Assert
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Equal(t, tt.wantDecline, errors.Is(err, ErrPaymentDeclined))
+				return
+			}
+			require.NoError(t, err)
+			assert.NotEmpty(t, payment.ID)
+			assert.Equal(t, int64(4550), payment.Amount)
+
+			again, err := provider.Charge(t.Context(), req)
+			require.NoError(t, err)
+			assert.Equal(t, payment, again, "an order is charged once")
+		})
+	}
+}
+
+func TestFakePaymentProviderForgetsOldestPayments(t *testing.T) {
+	// Arrange
+	provider := NewFakePaymentProvider(FakeProviderSettings{RememberedPayments: 2})
+	charge := func(key string) Payment {
+		payment, err := provider.Charge(t.Context(), PaymentRequest{IdempotencyKey: key, OrderID: key, Amount: 4550, Currency: "EUR"})
+		require.NoError(t, err)
+		return payment
+	}
+
+	// Act
+	first := charge("1")
+	second := charge("2")
+	secondAgain := charge("2")
+	charge("3")
+	firstAgain := charge("1")
+
+	// Assert
+	assert.Equal(t, second, secondAgain, "remembered payments are returned again")
+	assert.NotEqual(t, first.ID, firstAgain.ID, "the oldest payment was forgotten")
+	assert.Len(t, provider.payments, 2)
+}
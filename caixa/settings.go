@@ -0,0 +1,47 @@
+package main
+
+import (
+	_ "embed"
+
+	"github.com/taldoflemis/box-box/pacchetto"
+)
+
+//go:embed base.yaml
+var baseConfig []byte
+
+// FakeProviderSettings makes the fake provider behave like a flaky card
+// acquirer. Every charge takes LatencyInMilliseconds, some take
+// DelayInMilliseconds longer, some are declined and some fail outright.
+// RememberedPayments bounds the payments kept to answer repeated charges.
+type FakeProviderSettings struct {
+	LatencyInMilliseconds int     `mapstructure:"latency-in-milliseconds" validate:"min=0"`
+	ProbabilityOfDelay    float64 `mapstructure:"probability-of-delay" validate:"gte=0,lte=1"`
+	DelayInMilliseconds   int     `mapstructure:"delay-in-milliseconds" validate:"min=0"`
+	ProbabilityOfDecline  float64 `mapstructure:"probability-of-decline" validate:"gte=0,lte=1"`
+	ProbabilityOfError    float64 `mapstructure:"probability-of-error" validate:"gte=0,lte=1"`
+	RememberedPayments    int     `mapstructure:"remembered-payments" validate:"required,min=1"`
+}
+
+type CaixaSettings struct {
+	// Provider charges the orders, only fake exists for now
+	Provider               string               `mapstructure:"provider" validate:"required,oneof=fake"`
+	Fake                   FakeProviderSettings `mapstructure:"fake"`
+	OrderBatchSize         int                  `mapstructure:"order-batch-size" validate:"required,min=1"`
+	FetchMaxWaitInSeconds  int                  `mapstructure:"fetch-max-wait-in-seconds" validate:"required,min=1"`
+	ChargeTimeoutInSeconds int                  `mapstructure:"charge-timeout-in-seconds" validate:"required,min=1"`
+	// InProgressIntervalInSeconds is how often a message is kept in progress
+	// while its order is charged
+	InProgressIntervalInSeconds int `mapstructure:"in-progress-interval-in-seconds" validate:"required,min=1,max=29"`
+	// MaxAttempts is how often a charge that failed with a provider error is tried
+	// before the payment fails, declined payments are never retried
+	MaxAttempts         int `mapstructure:"max-attempts" validate:"required,min=1"`
+	RetryDelayInSeconds int `mapstructure:"retry-delay-in-seconds" validate:"required,min=1"`
+}
+
+type Settings struct {
+	App           pacchetto.AppSettings           `mapstructure:"app" validate:"required"`
+	Caixa         CaixaSettings                   `mapstructure:"caixa" validate:"required"`
+	Nats          pacchetto.NatsSettings          `mapstructure:"nats" validate:"required"`
+	OpenTelemetry pacchetto.OpenTelemetrySettings `mapstructure:"opentelemetry" validate:"required"`
+	GRPCServer    pacchetto.GRPCServerSettings    `mapstructure:"grpc-server" validate:"required"`
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/taldoflemis/box-box/pacchetto"
+)
+
+const (
+	statusDeadLetter    = "dead_letter"
+	statusWaitingToCook = "waiting_to_cook"
+)
+
+var (
+	ErrDeadLetterNotFound = errors.New("dead letter not found")
+	// ErrNotRedrivable is returned for malformed payloads, maestro would only dead-letter them again
+	ErrNotRedrivable = errors.New("dead letter cannot be redriven")
+)
+
+// DeadLetter is a message maestro gave up on, as republished to the
+// dead-letter subject with the failure in its headers.
+type DeadLetter struct {
+	Sequence         uint64
+	Subject          string
+	OrderID          string
+	Reason           string
+	Error            string
+	OriginalSubject  string
+	OriginalSequence string
+	Consumer         string
+	Deliveries       int
+	FailedAt         time.Time
+	Header           nats.Header
+	Data             []byte
+}
+
+func parseDeadLetter(msg *jetstream.RawStreamMsg) DeadLetter {
+	dl := DeadLetter{
+		Sequence:         msg.Sequence,
+		Subject:          msg.Subject,
+		OrderID:          msg.Subject[strings.LastIndex(msg.Subject, ".")+1:],
+		Reason:           msg.Header.Get(pacchetto.DeadLetterReasonHeader),
+		Error:            msg.Header.Get(pacchetto.DeadLetterErrorHeader),
+		OriginalSubject:  msg.Header.Get(pacchetto.DeadLetterSubjectHeader),
+		OriginalSequence: msg.Header.Get(pacchetto.DeadLetterSequenceHeader),
+		Consumer:         msg.Header.Get(pacchetto.DeadLetterConsumerHeader),
+		Header:           msg.Header,
+		Data:             msg.Data,
+	}
+
+	// Missing or broken headers are shown empty, the message is listed anyway
+	dl.Deliveries, _ = strconv.Atoi(msg.Header.Get(pacchetto.DeadLetterDeliveriesHeader))
+	dl.FailedAt, _ = time.Parse(time.RFC3339, msg.Header.Get(pacchetto.DeadLetterFailedAtHeader))
+
+	return dl
+}
+
+type deadLetterQueue struct {
+	settings DLQSettings
+	js       jetstream.JetStream
+	stream   jetstream.Stream
+	statusKV jetstream.KeyValue
+}
+
+func newDeadLetterQueue(ctx context.Context, settings DLQSettings, nc *nats.Conn) (*deadLetterQueue, error) {
+	js, err := jetstream.New(nc, jetstream.WithDefaultTimeout(time.Duration(settings.TimeoutInSeconds)*time.Second))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create jetstream context: %w", err)
+	}
+
+	stream, err := js.Stream(ctx, settings.Stream)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stream %s: %w", settings.Stream, err)
+	}
+
+	statusKV, err := js.KeyValue(ctx, settings.StatusBucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order status bucket %s: %w", settings.StatusBucket, err)
+	}
+
+	return &deadLetterQueue{
+		settings: settings,
+		js:       js,
+		stream:   stream,
+		statusKV: statusKV,
+	}, nil
+}
+
+func (q *deadLetterQueue) subject(token string) string {
+	return fmt.Sprintf("%s.%s.%s", q.settings.Subject, statusDeadLetter, token)
+}
+
+// List returns every dead letter in the stream, oldest first.
+func (q *deadLetterQueue) List(ctx context.Context) ([]DeadLetter, error) {
+	info, err := q.stream.Info(ctx, jetstream.WithSubjectFilter(q.subject("*")))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead-letter subjects: %w", err)
+	}
+
+	deadLetters := make([]DeadLetter, 0, len(info.State.Subjects))
+	for subject := range info.State.Subjects {
+		msg, err := q.stream.GetLastMsgForSubject(ctx, subject)
+		if errors.Is(err, jetstream.ErrMsgNotFound) {
+			// Redriven meanwhile
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get dead letter %s: %w", subject, err)
+		}
+
+		deadLetters = append(deadLetters, parseDeadLetter(msg))
+	}
+
+	slices.SortFunc(deadLetters, func(a, b DeadLetter) int {
+		return cmp.Compare(a.Sequence, b.Sequence)
+	})
+
+	return deadLetters, nil
+}
+
+// Get returns a dead letter by its stream sequence or by order ID.
+func (q *deadLetterQueue) Get(ctx context.Context, ref string) (DeadLetter, error) {
+	var (
+		msg *jetstream.RawStreamMsg
+		err error
+	)
+
+	if seq, parseErr := strconv.ParseUint(ref, 10, 64); parseErr == nil {
+		msg, err = q.stream.GetMsg(ctx, seq)
+		if err == nil && !strings.HasPrefix(msg.Subject, q.subject("")) {
+			return DeadLetter{}, fmt.Errorf("%w: sequence %d is %s", ErrDeadLetterNotFound, seq, msg.Subject)
+		}
+	} else {
+		msg, err = q.stream.GetLastMsgForSubject(ctx, q.subject(ref))
+	}
+	if errors.Is(err, jetstream.ErrMsgNotFound) {
+		return DeadLetter{}, fmt.Errorf("%w: %s", ErrDeadLetterNotFound, ref)
+	}
+	if err != nil {
+		return DeadLetter{}, fmt.Errorf("failed to get dead letter %s: %w", ref, err)
+	}
+
+	return parseDeadLetter(msg), nil
+}
+
+// Redrive sends the order of the dead letter back to maestro and removes the
+// dead letter from the stream.
+func (q *deadLetterQueue) Redrive(ctx context.Context, dl DeadLetter) error {
+	if dl.Reason == pacchetto.DeadLetterReasonMalformed {
+		return fmt.Errorf("%w: %d is a malformed payload", ErrNotRedrivable, dl.Sequence)
+	}
+
+	data, orderID, err := redrivePayload(dl.Data, time.Now())
+	if err != nil {
+		return fmt.Errorf("%w: %d: %w", ErrNotRedrivable, dl.Sequence, err)
+	}
+
+	msg := &nats.Msg{
+		Subject: fmt.Sprintf("%s.%s.%s", q.settings.Subject, statusWaitingToCook, orderID),
+		Header:  nats.Header{},
+		Data:    data,
+	}
+	// Unique per dead letter, so running the same redrive twice sends the order once
+	msg.Header.Set(jetstream.MsgIDHeader, fmt.Sprintf("%s.%s.redrive.%d", orderID, statusWaitingToCook, dl.Sequence))
+
+	_, err = q.js.PublishMsg(ctx, msg)
+	if err != nil {
+		return fmt.Errorf("failed to publish order %s: %w", orderID, err)
+	}
+
+	_, err = q.statusKV.Put(ctx, orderID, data)
+	if err != nil {
+		return fmt.Errorf("failed to update order status bucket for %s: %w", orderID, err)
+	}
+
+	err = q.stream.DeleteMsg(ctx, dl.Sequence)
+	if err != nil {
+		return fmt.Errorf("order %s was redriven but its dead letter %d was not deleted: %w", orderID, dl.Sequence, err)
+	}
+
+	return nil
+}
+
+// redrivePayload turns a dead-lettered order back into an order waiting to
+// cook. The order is handled as a JSON object so fields this tool does not
+// know survive the trip.
+func redrivePayload(data []byte, now time.Time) ([]byte, string, error) {
+	var order map[string]any
+	err := json.Unmarshal(data, &order)
+	if err != nil {
+		return nil, "", fmt.Errorf("payload is not an order: %w", err)
+	}
+
+	orderID, _ := order["order_id"].(string)
+	if orderID == "" {
+		return nil, "", errors.New("payload has no order_id")
+	}
+
+	order["status"] = statusWaitingToCook
+	delete(order, "reason")
+
+	timestamps, _ := order["timestamps"].(map[string]any)
+	if timestamps == nil {
+		timestamps = make(map[string]any)
+	}
+	timestamps[statusWaitingToCook] = now
+	order["timestamps"] = timestamps
+
+	data, err = json.Marshal(order)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return data, orderID, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedrivePayload(t *testing.T) {
+	now := time.Date(2025, 9, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		data        string
+		wantOrderID string
+		wantErr     bool
+	}{
+		{
+			name:        "dead-lettered order",
+			data:        `{"order_id":"abc","status":"dead_letter","reason":"fornaio unavailable","timestamps":{"dead_letter":"2025-09-10T11:00:00Z"},"destination":"Monza"}`,
+			wantOrderID: "abc",
+		},
+		{
+			name:        "order without timestamps",
+			data:        `{"order_id":"abc","status":"dead_letter"}`,
+			wantOrderID: "abc",
+		},
+		{
+			name:    "not an order",
+			data:    `pizza`,
+			wantErr: true,
+		},
+		{
+			name:    "no order id",
+			data:    `{"status":"dead_letter"}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			// Act
+			data, orderID, err := redrivePayload([]byte(tt.data), now)
+
+			// Assert
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantOrderID, orderID)
+
+			var order map[string]any
+			require.NoError(t, json.Unmarshal(data, &order))
+			assert.Equal(t, statusWaitingToCook, order["status"])
+			assert.NotContains(t, order, "reason")
+			assert.Equal(t, now.Format(time.RFC3339), order["timestamps"].(map[string]any)[statusWaitingToCook])
+		})
+	}
+}
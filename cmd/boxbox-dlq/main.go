@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/taldoflemis/box-box/pacchetto"
+)
+
+const usage = `boxbox-dlq inspects and redrives the orders maestro dead-lettered.
+
+Usage:
+  boxbox-dlq list
+  boxbox-dlq show <sequence|order-id>
+  boxbox-dlq redrive [-dry-run] [-rate n] (-all | <sequence|order-id>...)
+
+Settings are read from the environment with the BOXBOXDLQ prefix,
+e.g. BOXBOXDLQ_NATS_HOST.
+`
+
+var errUsage = errors.New("usage")
+
+func main() {
+	ctx, stop := signal.NotifyContext(
+		context.Background(),
+		os.Interrupt,
+		syscall.SIGINT,
+		syscall.SIGTERM,
+	)
+	defer stop()
+
+	err := run(ctx, os.Args[1:], os.Stdout)
+	if errors.Is(err, errUsage) {
+		fmt.Fprint(os.Stderr, usage)
+		stop()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "boxbox-dlq:", err)
+		stop()
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	settings, err := pacchetto.LoadConfig[Settings]("BOXBOXDLQ", baseConfig)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	nc, err := settings.Nats.GetNatsClient()
+	if err != nil {
+		return fmt.Errorf("failed to connect to NATS server: %w", err)
+	}
+	defer nc.Close()
+
+	queue, err := newDeadLetterQueue(ctx, settings.DLQ, nc)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "list":
+		return list(ctx, queue, out)
+	case "show":
+		if len(args) != 2 {
+			return errUsage
+		}
+		return show(ctx, queue, args[1], out)
+	case "redrive":
+		return redrive(ctx, queue, args[1:], out)
+	default:
+		return errUsage
+	}
+}
+
+func list(ctx context.Context, queue *deadLetterQueue, out io.Writer) error {
+	deadLetters, err := queue.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SEQ\tORDER\tREASON\tDELIVERIES\tFAILED AT\tERROR")
+	for _, dl := range deadLetters {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\t%s\n",
+			dl.Sequence, dl.OrderID, dl.Reason, dl.Deliveries, formatTime(dl.FailedAt), dl.Error)
+	}
+
+	return w.Flush()
+}
+
+func show(ctx context.Context, queue *deadLetterQueue, ref string, out io.Writer) error {
+	dl, err := queue.Get(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Sequence:\t%d\n", dl.Sequence)
+	fmt.Fprintf(w, "Subject:\t%s\n", dl.Subject)
+	fmt.Fprintf(w, "Order:\t%s\n", dl.OrderID)
+	fmt.Fprintf(w, "Reason:\t%s\n", dl.Reason)
+	fmt.Fprintf(w, "Error:\t%s\n", dl.Error)
+	fmt.Fprintf(w, "Original subject:\t%s\n", dl.OriginalSubject)
+	fmt.Fprintf(w, "Original sequence:\t%s\n", dl.OriginalSequence)
+	fmt.Fprintf(w, "Consumer:\t%s\n", dl.Consumer)
+	fmt.Fprintf(w, "Deliveries:\t%d\n", dl.Deliveries)
+	fmt.Fprintf(w, "Failed at:\t%s\n", formatTime(dl.FailedAt))
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "\n%s\n", dl.Data)
+
+	return nil
+}
+
+func redrive(ctx context.Context, queue *deadLetterQueue, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("redrive", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	all := flags.Bool("all", false, "redrive every dead letter")
+	dryRun := flags.Bool("dry-run", false, "print what would be redriven without publishing")
+	rate := flags.Float64("rate", 10, "dead letters redriven per second")
+
+	err := flags.Parse(args)
+	if err != nil || *rate <= 0 || *all == (flags.NArg() > 0) {
+		return errUsage
+	}
+
+	var deadLetters []DeadLetter
+	if *all {
+		deadLetters, err = queue.List(ctx)
+		if err != nil {
+			return err
+		}
+	} else {
+		for _, ref := range flags.Args() {
+			dl, err := queue.Get(ctx, ref)
+			if err != nil {
+				return err
+			}
+			deadLetters = append(deadLetters, dl)
+		}
+	}
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / *rate))
+	defer ticker.Stop()
+
+	var redriven, skipped int
+	for i, dl := range deadLetters {
+		if i > 0 && !*dryRun {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+			}
+		}
+
+		if dl.Reason == pacchetto.DeadLetterReasonMalformed {
+			fmt.Fprintf(out, "skip %d: malformed payload\n", dl.Sequence)
+			skipped++
+			continue
+		}
+
+		if *dryRun {
+			fmt.Fprintf(out, "would redrive %d: order %s\n", dl.Sequence, dl.OrderID)
+			redriven++
+			continue
+		}
+
+		err := queue.Redrive(ctx, dl)
+		if errors.Is(err, ErrNotRedrivable) {
+			fmt.Fprintf(out, "skip %d: %v\n", dl.Sequence, err)
+			skipped++
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "redrove %d: order %s\n", dl.Sequence, dl.OrderID)
+		redriven++
+	}
+
+	verb := "redriven"
+	if *dryRun {
+		verb = "to redrive"
+	}
+	fmt.Fprintf(out, "%d %s, %d skipped\n", redriven, verb, skipped)
+
+	return nil
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+
+	return t.Local().Format(time.DateTime)
+}
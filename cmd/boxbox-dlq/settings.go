@@ -0,0 +1,23 @@
+package main
+
+import (
+	_ "embed"
+
+	"github.com/taldoflemis/box-box/pacchetto"
+)
+
+//go:embed base.yaml
+var baseConfig []byte
+
+type DLQSettings struct {
+	Stream  string `mapstructure:"stream" validate:"required"`
+	Subject string `mapstructure:"subject" validate:"required"`
+	// StatusBucket is refreshed with the status of every redriven order
+	StatusBucket     string `mapstructure:"status-bucket" validate:"required"`
+	TimeoutInSeconds int    `mapstructure:"timeout-in-seconds" validate:"required,min=1"`
+}
+
+type Settings struct {
+	DLQ  DLQSettings            `mapstructure:"dlq" validate:"required"`
+	Nats pacchetto.NatsSettings `mapstructure:"nats" validate:"required"`
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/taldoflemis/box-box/pacchetto"
+	"github.com/taldoflemis/box-box/pacchetto/telemetry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	statusOutForDelivery = "out_for_delivery"
+	statusDelivered      = "delivered"
+)
+
+type Order = pacchetto.Order
+
+type corriereHandler struct {
+	settings CorriereSettings
+	// riders holds the riders waiting at the pizzeria
+	riders chan string
+	// random returns a number in [0, 1), replaced in tests
+	random           func() float64
+	consumer         jetstream.Consumer
+	statuses         *pacchetto.OrderStatusPublisher
+	deliveryCounter  metric.Int64Counter
+	deliveryDuration metric.Float64Histogram
+	busyRiders       metric.Int64UpDownCounter
+}
+
+var (
+	tracer = otel.Tracer("corriere")
+	meter  = otel.Meter("corriere")
+)
+
+func newCorriereHandler(settings CorriereSettings,
+	nc *nats.Conn,
+	streamName string,
+	subject string,
+	statusBucket string,
+) (*corriereHandler, error) {
+	ctx := context.Background()
+
+	deliveryCounter, err := meter.Int64Counter(
+		"corriere.delivery.count",
+		metric.WithDescription("Number of orders the riders have delivered"),
+		metric.WithUnit("{delivery}"),
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to create delivery counter", slog.Any("err", err))
+		return nil, err
+	}
+
+	deliveryDuration, err := meter.Float64Histogram(
+		"corriere.delivery.duration",
+		metric.WithDescription("Time between an order leaving the pizzeria and reaching its destination"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to create delivery histogram", slog.Any("err", err))
+		return nil, err
+	}
+
+	busyRiders, err := meter.Int64UpDownCounter(
+		"corriere.riders.busy",
+		metric.WithDescription("Number of riders away from the pizzeria"),
+		metric.WithUnit("{rider}"),
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to create busy riders counter", slog.Any("err", err))
+		return nil, err
+	}
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to create jetstream context", slog.Any("err", err))
+		return nil, err
+	}
+
+	stream, err := js.Stream(ctx, streamName)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to get stream", slog.Any("err", err))
+		return nil, err
+	}
+
+	statusKV, err := pacchetto.CreateOrderStatusBucket(ctx, js, statusBucket)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to create order status bucket", slog.Any("err", err))
+		return nil, err
+	}
+
+	c, err := stream.CreateOrUpdateConsumer(ctx, jetstream.ConsumerConfig{
+		Durable:       streamName + "_corriere_delivery_listener_v1",
+		FilterSubject: fmt.Sprintf("%s.waiting_delivery.*", subject),
+		AckPolicy:     jetstream.AckExplicitPolicy,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to create consumer", slog.Any("err", err))
+		return nil, err
+	}
+
+	return &corriereHandler{
+		settings:         settings,
+		riders:           newRiderPool(settings.Riders),
+		random:           rand.Float64,
+		consumer:         c,
+		statuses:         pacchetto.NewOrderStatusPublisher(js, statusKV, subject),
+		deliveryCounter:  deliveryCounter,
+		deliveryDuration: deliveryDuration,
+		busyRiders:       busyRiders,
+	}, nil
+}
+
+func newRiderPool(size int) chan string {
+	riders := make(chan string, size)
+	for i := range size {
+		riders <- fmt.Sprintf("rider-%d", i+1)
+	}
+	return riders
+}
+
+func (h *corriereHandler) startShift(ctx context.Context) {
+	slog.InfoContext(ctx, "Corriere riders are starting their shift", slog.Int("riders", h.settings.Riders))
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "Corriere riders ended their shift")
+			return
+		default:
+			msgs, err := h.consumer.Fetch(h.settings.OrderBatchSize,
+				jetstream.FetchMaxWait(time.Duration(h.settings.FetchMaxWaitInSeconds)*time.Second),
+			)
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to consume messages", slog.Any("err", err))
+				continue
+			}
+
+			for msg := range msgs.Messages() {
+				rider, ok := h.waitForRider(ctx, msg)
+				if !ok {
+					continue
+				}
+
+				go h.deliver(context.Background(), msg, rider)
+			}
+		}
+	}
+}
+
+// waitForRider blocks until a rider is back at the pizzeria, keeping the
+// message from being redelivered meanwhile.
+func (h *corriereHandler) waitForRider(ctx context.Context, msg jetstream.Msg) (string, bool) {
+	ticker := time.NewTicker(time.Duration(h.settings.InProgressIntervalInSeconds) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case rider := <-h.riders:
+			h.busyRiders.Add(ctx, 1)
+			return rider, true
+		case <-ticker.C:
+			h.inProgress(ctx, msg)
+		case <-ctx.Done():
+			// Let another replica deliver the order
+			err := msg.Nak()
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to nak message", slog.Any("err", err))
+			}
+			return "", false
+		}
+	}
+}
+
+func (h *corriereHandler) releaseRider(ctx context.Context, rider string) {
+	h.busyRiders.Add(ctx, -1)
+	h.riders <- rider
+}
+
+func (h *corriereHandler) deliver(ctx context.Context, msg jetstream.Msg, rider string) {
+	ctx = telemetry.GetContextFromJetstreamMsg(ctx, msg)
+	ctx, span := tracer.Start(ctx, "corriereHandler.deliver", trace.WithAttributes(
+		attribute.String("corriere.rider", rider),
+	))
+	defer span.End()
+
+	var order Order
+	err := json.Unmarshal(msg.Data(), &order)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to unmarshal order from NATS message", slog.Any("err", err))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		h.releaseRider(ctx, rider)
+		h.term(ctx, msg)
+		return
+	}
+
+	distance := distanceInKm(order.Destination, h.settings.MaxDistanceInKm)
+	trip := h.travelTime(distance)
+
+	span.SetAttributes(
+		attribute.String("box-box.orderid", order.OrderID),
+		attribute.String("order.destination", order.Destination),
+		attribute.Int("corriere.distance-in-km", distance),
+		attribute.String("corriere.trip", trip.String()),
+	)
+
+	order.Rider = rider
+	if order.Timestamps == nil {
+		// Shared with the status publisher so the delivered event keeps the departure time
+		order.Timestamps = make(map[string]time.Time)
+	}
+	err = h.statuses.Publish(ctx, order, statusOutForDelivery)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to publish order out for delivery", slog.String("order-id", order.OrderID), slog.Any("err", err))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "failed to publish order out for delivery")
+		h.releaseRider(ctx, rider)
+		return
+	}
+
+	slog.InfoContext(ctx, "Order is out for delivery",
+		slog.String("order-id", order.OrderID),
+		slog.String("rider", rider),
+		slog.String("destination", order.Destination),
+		slog.Int("distance-in-km", distance),
+		slog.Duration("trip", trip))
+
+	start := time.Now()
+	h.ride(ctx, msg, trip+time.Duration(h.settings.HandoffDurationInSeconds)*time.Second)
+
+	err = h.statuses.Publish(ctx, order, statusDelivered)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to publish delivered order", slog.String("order-id", order.OrderID), slog.Any("err", err))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "failed to publish delivered order")
+	} else {
+		h.deliveryCounter.Add(ctx, 1)
+		h.deliveryDuration.Record(ctx, time.Since(start).Seconds())
+		slog.InfoContext(ctx, "Order delivered", slog.String("order-id", order.OrderID), slog.String("rider", rider))
+
+		err = msg.Ack()
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to acknowledge message", slog.Any("err", err))
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+
+	// The rider still has to ride back before taking another order
+	time.Sleep(trip)
+	h.releaseRider(ctx, rider)
+}
+
+// ride waits for the rider to reach the destination, keeping the message from
+// being redelivered on the way.
+func (h *corriereHandler) ride(ctx context.Context, msg jetstream.Msg, trip time.Duration) {
+	ticker := time.NewTicker(time.Duration(h.settings.InProgressIntervalInSeconds) * time.Second)
+	defer ticker.Stop()
+
+	arrival := time.After(trip)
+	for {
+		select {
+		case <-arrival:
+			return
+		case <-ticker.C:
+			h.inProgress(ctx, msg)
+		}
+	}
+}
+
+// distanceInKm places destination between 1 and maxDistance km away from the
+// pizzeria. The same destination is always at the same distance.
+func distanceInKm(destination string, maxDistance int) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(strings.ToLower(strings.TrimSpace(destination))))
+	return int(hash.Sum32()%uint32(maxDistance)) + 1
+}
+
+// travelTime is how long a rider takes to ride distance km one way, with a
+// random variance between 1/factor and factor times the usual time.
+func (h *corriereHandler) travelTime(distance int) time.Duration {
+	base := time.Duration(distance*h.settings.TravelTimePerKmInSeconds) * time.Second
+
+	minFactor := 1.0 / h.settings.VarianceInTravelTimeFactor
+	maxFactor := h.settings.VarianceInTravelTimeFactor
+	randomFactor := minFactor + h.random()*(maxFactor-minFactor)
+
+	return time.Duration(float64(base) * randomFactor)
+}
+
+func (h *corriereHandler) inProgress(ctx context.Context, msg jetstream.Msg) {
+	err := msg.InProgress()
+	if err != nil {
+		slog.WarnContext(ctx, "failed to set message in progress", slog.Any("err", err))
+	}
+}
+
+func (h *corriereHandler) term(ctx context.Context, msg jetstream.Msg) {
+	err := msg.Term()
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to terminate message", slog.Any("err", err))
+		trace.SpanFromContext(ctx).RecordError(err)
+	}
+}
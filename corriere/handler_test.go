@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistanceInKm(t *testing.T) {
+	destinations := []string{"Ferrari Garage #16", "Hospitality Suite", "Parc Fermé", ""}
+
+	for _, destination := range destinations {
+		t.Run(destination, func(t *testing.T) {
+			// Act
+			distance := distanceInKm(destination, 10)
+
+			// Assert
+			assert.GreaterOrEqual(t, distance, 1)
+			assert.LessOrEqual(t, distance, 10)
+			assert.Equal(t, distance, distanceInKm("  "+destination+" ", 10), "the same destination is always at the same distance")
+		})
+	}
+}
+
+func TestTravelTime(t *testing.T) {
+	tests := []struct {
+		name   string
+		random float64
+		want   time.Duration
+	}{
+		{name: "light traffic", random: 0, want: 5 * time.Second},
+		{name: "usual traffic", random: 1.0 / 3, want: 10 * time.Second},
+		{name: "heavy traffic", random: 1, want: 20 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			h := &corriereHandler{
+				settings: CorriereSettings{TravelTimePerKmInSeconds: 2, VarianceInTravelTimeFactor: 2},
+				random:   func() float64 { return tt.random },
+			}
+
+			// Act
+			got := h.travelTime(5)
+
+			// Assert
+			assert.InDelta(t, tt.want.Seconds(), got.Seconds(), 0.001)
+		})
+	}
+}
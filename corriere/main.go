@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/taldoflemis/box-box/pacchetto"
+	"github.com/taldoflemis/box-box/pacchetto/telemetry"
+	"google.golang.org/grpc/health"
+	healthgrpc "google.golang.org/grpc/health/grpc_health_v1"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(
+		context.Background(),
+		os.Interrupt,
+		syscall.SIGINT,
+		syscall.SIGTERM,
+	)
+	defer stop()
+	retcode := 0
+	defer func() {
+		os.Exit(retcode)
+	}()
+
+	slog.InfoContext(ctx, "Launching corriere")
+
+	slog.InfoContext(ctx, "Loading config")
+	settings, err := pacchetto.LoadConfig[Settings]("CORRIERE", baseConfig)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to load config", slog.Any("err", err))
+		retcode = 1
+		return
+	}
+
+	slog.InfoContext(ctx, "Setting up opentelemetry")
+	otelShutdown, err := telemetry.SetupOTelSDK(ctx, settings.App, settings.OpenTelemetry)
+	if err != nil {
+		slog.Error("failed to setup telemetry", slog.Any("err", err))
+		retcode = 1
+		return
+	}
+
+	defer func() {
+		err = errors.Join(err, otelShutdown(context.Background()))
+		if err != nil {
+			slog.ErrorContext(
+				ctx,
+				"failed to shutdown opentelemetry providers",
+				slog.Any("err", err),
+			)
+			retcode = 1
+		}
+	}()
+
+	slog.InfoContext(ctx, "Corriere settings", slog.Any("settings", settings.Corriere))
+
+	slog.InfoContext(ctx, "Connecting to NATS server")
+	nc, err := settings.Nats.GetNatsClient()
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to connect to NATS server", slog.Any("err", err))
+		retcode = 1
+		return
+	}
+
+	streamName := "ORDERS"
+	subject := "orders"
+	statusBucket := "ORDERS_STATUS"
+	corriere, err := newCorriereHandler(settings.Corriere, nc, streamName, subject, statusBucket)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to create corriere handler", slog.Any("err", err))
+		retcode = 1
+		return
+	}
+
+	slog.InfoContext(ctx, "Creating gRPC server")
+	server := pacchetto.CreateGRPCServer()
+	healthcheck := health.NewServer()
+	healthgrpc.RegisterHealthServer(server, healthcheck)
+
+	if settings.GRPCServer.EnableReflection {
+		reflection.Register(server)
+	}
+
+	go func() {
+		// asynchronously inspect dependencies and toggle serving status as needed
+		status := healthpb.HealthCheckResponse_SERVING
+		sleepDuration := time.Duration(settings.GRPCServer.AsyncHealthIntervalInSeconds) * time.Second
+
+		system := ""
+
+		for {
+			healthcheck.SetServingStatus(system, status)
+
+			if !nc.IsConnected() {
+				status = healthpb.HealthCheckResponse_NOT_SERVING
+			} else {
+				status = healthpb.HealthCheckResponse_SERVING
+			}
+
+			time.Sleep(sleepDuration)
+		}
+	}()
+
+	lis, err := net.Listen("tcp", fmt.Sprintf("%s:%s", settings.GRPCServer.Host, strconv.Itoa(settings.GRPCServer.Port)))
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to listen", slog.Any("err", err))
+		retcode = 1
+		return
+	}
+
+	slog.InfoContext(ctx, "Starting gRPC server", slog.Any("addr", lis.Addr()))
+
+	errChan := make(chan error)
+	go func() {
+		err := server.Serve(lis)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to serve", slog.Any("err", err))
+			errChan <- err
+		}
+	}()
+
+	go func() {
+		corriere.startShift(ctx)
+	}()
+
+	select {
+	case err := <-errChan:
+		slog.ErrorContext(ctx, "gRPC server stopped", slog.Any("err", err))
+		break
+	case <-ctx.Done():
+		// Wait for first Signal arrives
+	}
+
+	slog.InfoContext(ctx, "Shutting down gRPC server")
+	server.GracefulStop()
+	slog.InfoContext(ctx, "gRPC server stopped")
+}
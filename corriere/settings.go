@@ -0,0 +1,31 @@
+package main
+
+import (
+	_ "embed"
+
+	"github.com/taldoflemis/box-box/pacchetto"
+)
+
+//go:embed base.yaml
+var baseConfig []byte
+
+type CorriereSettings struct {
+	// Riders is the size of the pool, every rider carries one order at a time
+	Riders                int `mapstructure:"riders" validate:"required,min=1"`
+	OrderBatchSize        int `mapstructure:"order-batch-size" validate:"required,min=1"`
+	FetchMaxWaitInSeconds int `mapstructure:"fetch-max-wait-in-seconds" validate:"required,min=1"`
+	// Destinations are placed up to MaxDistanceInKm away from the pizzeria
+	MaxDistanceInKm             int     `mapstructure:"max-distance-in-km" validate:"required,min=1"`
+	TravelTimePerKmInSeconds    int     `mapstructure:"travel-time-per-km-in-seconds" validate:"required,min=1"`
+	VarianceInTravelTimeFactor  float64 `mapstructure:"variance-in-travel-time-factor" validate:"required,min=1,max=2"`
+	HandoffDurationInSeconds    int     `mapstructure:"handoff-duration-in-seconds" validate:"min=0"`
+	InProgressIntervalInSeconds int     `mapstructure:"in-progress-interval-in-seconds" validate:"required,min=1,max=29"`
+}
+
+type Settings struct {
+	App           pacchetto.AppSettings           `mapstructure:"app" validate:"required"`
+	Corriere      CorriereSettings                `mapstructure:"corriere" validate:"required"`
+	Nats          pacchetto.NatsSettings          `mapstructure:"nats" validate:"required"`
+	OpenTelemetry pacchetto.OpenTelemetrySettings `mapstructure:"opentelemetry" validate:"required"`
+	GRPCServer    pacchetto.GRPCServerSettings    `mapstructure:"grpc-server" validate:"required"`
+}
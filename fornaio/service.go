@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"sync"
+	"time"
+
+	fornaiov1pb "github.com/taldoflemis/box-box/fornaio/v1"
+	panettierev1pb "github.com/taldoflemis/box-box/panettiere/v1"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+var (
+	tracer = otel.Tracer("fornaio")
+	meter  = otel.Meter("fornaio")
+)
+
+type fornaioService struct {
+	fornaiov1pb.UnimplementedFornaioServiceServer
+	settings FornaioSettings
+	// slots holds a token for every pizza in the oven
+	slots chan struct{}
+	// random returns a number in [0, 1), replaced in tests
+	random func() float64
+
+	mu          sync.Mutex
+	onBreak     bool
+	shouldBreak bool
+	breakTicker *time.Ticker
+	ctx         context.Context
+	cancel      context.CancelFunc
+
+	bakeCounter   metric.Int64Counter
+	bakeHistogram metric.Float64Histogram
+	breakCounter  metric.Int64Counter
+}
+
+var _ fornaiov1pb.FornaioServiceServer = (*fornaioService)(nil)
+
+func newFornaioService(settings FornaioSettings) (*fornaioService, error) {
+	bakeCounter, err := meter.Int64Counter(
+		"fornaio.bake.count",
+		metric.WithDescription("Number of pizzas baked, by whether they came out burnt"),
+		metric.WithUnit("{pizza}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	bakeHistogram, err := meter.Float64Histogram(
+		"fornaio.bake.duration",
+		metric.WithDescription("Time the pizzas spent in the oven"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	breakCounter, err := meter.Int64Counter(
+		"fornaio.break.count",
+		metric.WithDescription("Number of breaks the fornaio has taken"),
+		metric.WithUnit("{call}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	service := &fornaioService{
+		settings:      settings,
+		slots:         make(chan struct{}, settings.OvenSlots),
+		random:        rand.Float64,
+		ctx:           ctx,
+		cancel:        cancel,
+		bakeCounter:   bakeCounter,
+		bakeHistogram: bakeHistogram,
+		breakCounter:  breakCounter,
+	}
+
+	service.startBreakTicker()
+
+	return service, nil
+}
+
+func (f *fornaioService) startBreakTicker() {
+	f.breakTicker = time.NewTicker(time.Duration(f.settings.PeriodBetweenBreaksInSeconds) * time.Second)
+
+	go func() {
+		for {
+			select {
+			case <-f.breakTicker.C:
+				f.mu.Lock()
+				if len(f.slots) == 0 && !f.onBreak {
+					f.takeBreak()
+				} else if !f.onBreak {
+					// Pizzas in the oven cannot be left alone, stop taking new ones and leave once they are out
+					f.shouldBreak = true
+					slog.InfoContext(f.ctx, "Break timer triggered, fornaio will leave once the oven is empty", slog.Int("pizzas-in-oven", len(f.slots)))
+				}
+				f.mu.Unlock()
+			case <-f.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// takeBreak must be called with f.mu held.
+func (f *fornaioService) takeBreak() {
+	f.onBreak = true
+	f.shouldBreak = false
+
+	breakDuration := time.Duration(f.settings.BreakDurationInSeconds) * time.Second
+	if f.random() < f.settings.ProbabilityOfLongBreak {
+		breakDuration = time.Duration(float64(breakDuration) * f.settings.LongBreakFactor)
+		slog.InfoContext(f.ctx, "Fornaio is taking a long break!", slog.Duration("break-duration", breakDuration))
+	} else {
+		slog.InfoContext(f.ctx, "Fornaio is taking a break", slog.Duration("break-duration", breakDuration))
+	}
+
+	f.breakCounter.Add(f.ctx, 1)
+
+	go func() {
+		time.Sleep(breakDuration)
+		f.mu.Lock()
+		f.onBreak = false
+		f.mu.Unlock()
+		slog.InfoContext(f.ctx, "Fornaio is back at the oven")
+	}()
+}
+
+func (f *fornaioService) isOnBreak() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.onBreak
+}
+
+func (f *fornaioService) Stop() {
+	if f.breakTicker != nil {
+		f.breakTicker.Stop()
+	}
+	f.cancel()
+}
+
+// bakeTime is the time a pizza of the given size takes in the oven, before variance.
+func (s BakeTimeSettings) bakeTime(size panettierev1pb.PizzaSize) time.Duration {
+	switch size {
+	case panettierev1pb.PizzaSize_Large:
+		return time.Duration(s.LargeInSeconds) * time.Second
+	case panettierev1pb.PizzaSize_Medium:
+		return time.Duration(s.MediumInSeconds) * time.Second
+	default:
+		return time.Duration(s.SmallInSeconds) * time.Second
+	}
+}
+
+// Bake implements v1.FornaioServiceServer.
+func (f *fornaioService) Bake(ctx context.Context, req *fornaiov1pb.BakeRequest) (*fornaiov1pb.BakeResponse, error) {
+	ctx, span := tracer.Start(ctx, "fornaioService.Bake", trace.WithAttributes(
+		attribute.String("box-box.orderid", req.OrderId),
+		attribute.String("fornaio.size", req.Size.String()),
+	))
+	defer span.End()
+
+	f.mu.Lock()
+	if f.onBreak || f.shouldBreak {
+		f.mu.Unlock()
+		slog.WarnContext(ctx, "Cannot bake: fornaio is on a break", slog.String("order-id", req.OrderId))
+		return nil, status.Errorf(codes.Unavailable, "fornaio is on a break and cannot bake right now")
+	}
+
+	select {
+	case f.slots <- struct{}{}:
+	default:
+		f.mu.Unlock()
+		slog.WarnContext(ctx, "Cannot bake: the oven is full", slog.String("order-id", req.OrderId))
+		return nil, status.Errorf(codes.ResourceExhausted, "all %d oven slots are busy", cap(f.slots))
+	}
+	f.mu.Unlock()
+
+	defer func() {
+		f.mu.Lock()
+		<-f.slots
+		if f.shouldBreak && len(f.slots) == 0 && !f.onBreak {
+			f.takeBreak()
+		}
+		f.mu.Unlock()
+	}()
+
+	// Random variance between 1/factor and factor times the bake time of the size
+	minFactor := 1.0 / f.settings.VarianceInBakeTimeFactor
+	maxFactor := f.settings.VarianceInBakeTimeFactor
+	randomFactor := minFactor + f.random()*(maxFactor-minFactor)
+
+	baseBakeTime := f.settings.BakeTime.bakeTime(req.Size)
+	actualBakeTime := time.Duration(float64(baseBakeTime) * randomFactor)
+
+	slog.InfoContext(ctx, "Baking pizza",
+		slog.String("order-id", req.OrderId),
+		slog.Duration("base_time", baseBakeTime),
+		slog.Duration("actual_time", actualBakeTime))
+
+	select {
+	case <-ctx.Done():
+		// Maestro gave up on the pizza, take it out and free the slot
+		span.RecordError(ctx.Err())
+		return nil, status.FromContextError(ctx.Err()).Err()
+	case <-time.After(actualBakeTime):
+	}
+
+	burnt := f.random() < f.settings.ProbabilityOfBurning
+
+	f.bakeCounter.Add(ctx, 1, metric.WithAttributes(attribute.Bool("fornaio.burnt", burnt)))
+	f.bakeHistogram.Record(ctx, actualBakeTime.Seconds())
+	span.SetAttributes(attribute.Bool("fornaio.burnt", burnt))
+
+	content := "Pizza baked from " + req.Dough
+	if burnt {
+		content = "Pizza burnt from " + req.Dough
+		slog.WarnContext(ctx, "Pizza came out burnt", slog.String("order-id", req.OrderId))
+	} else {
+		slog.InfoContext(ctx, "Pizza is baked", slog.String("order-id", req.OrderId))
+	}
+
+	return &fornaiov1pb.BakeResponse{
+		Content:                content,
+		Burnt:                  burnt,
+		BakeTimeInMilliseconds: actualBakeTime.Milliseconds(),
+	}, nil
+}
+
+// Status implements v1.FornaioServiceServer.
+func (f *fornaioService) Status(context.Context, *emptypb.Empty) (*fornaiov1pb.StatusResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	baking := len(f.slots)
+
+	status := "idle"
+	switch {
+	case f.onBreak:
+		status = "on a break"
+	case f.shouldBreak:
+		status = "should take a break once the oven is empty"
+	case baking > 0:
+		status = fmt.Sprintf("baking %d pizzas", baking)
+	}
+
+	return &fornaiov1pb.StatusResponse{
+		Status:    status,
+		FreeSlots: int32(cap(f.slots) - baking),
+	}, nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	fornaiov1pb "github.com/taldoflemis/box-box/fornaio/v1"
+	panettierev1pb "github.com/taldoflemis/box-box/panettiere/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFornaioServiceBake(t *testing.T) {
+	tests := []struct {
+		name      string
+		prepare   func(f *fornaioService)
+		burning   float64
+		wantCode  codes.Code
+		wantBurnt bool
+	}{
+		{
+			name:     "baked",
+			wantCode: codes.OK,
+		},
+		{
+			name:      "burnt",
+			burning:   0.99,
+			wantCode:  codes.OK,
+			wantBurnt: true,
+		},
+		{
+			name: "oven full",
+			prepare: func(f *fornaioService) {
+				f.slots <- struct{}{}
+			},
+			wantCode: codes.ResourceExhausted,
+		},
+		{
+			name: "on a break",
+			prepare: func(f *fornaioService) {
+				f.onBreak = true
+			},
+			wantCode: codes.Unavailable,
+		},
+		{
+			name: "going on a break",
+			prepare: func(f *fornaioService) {
+				f.shouldBreak = true
+			},
+			wantCode: codes.Unavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			f, err := newFornaioService(FornaioSettings{
+				OvenSlots:                    1,
+				VarianceInBakeTimeFactor:     1,
+				ProbabilityOfBurning:         tt.burning,
+				PeriodBetweenBreaksInSeconds: 3600,
+			})
+			require.NoError(t, err)
+			t.Cleanup(f.Stop)
+			f.random = func() float64 { return 0.5 }
+			if tt.prepare != nil {
+				tt.prepare(f)
+			}
+			req := &fornaiov1pb.BakeRequest{OrderId: "123", Size: panettierev1pb.PizzaSize_Large, Dough: "Dough with NoBorder border, size Large"}
+
+			// Act
+			resp, err := f.Bake(context.Background(), req)
+
+			// Assert
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			if tt.wantCode != codes.OK {
+				return
+			}
+			assert.Equal(t, tt.wantBurnt, resp.Burnt)
+			assert.Contains(t, resp.Content, req.Dough)
+			assert.Empty(t, f.slots, "the slot is freed once the pizza is out")
+		})
+	}
+}
+
+func TestBakeTimeBySize(t *testing.T) {
+	settings := BakeTimeSettings{SmallInSeconds: 2, MediumInSeconds: 3, LargeInSeconds: 4}
+
+	tests := []struct {
+		size panettierev1pb.PizzaSize
+		want int
+	}{
+		{panettierev1pb.PizzaSize_Small, 2},
+		{panettierev1pb.PizzaSize_Medium, 3},
+		{panettierev1pb.PizzaSize_Large, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.size.String(), func(t *testing.T) {
+			// Act
+			got := settings.bakeTime(tt.size)
+
+			// Assert
+			assert.Equal(t, float64(tt.want), got.Seconds())
+		})
+	}
+}
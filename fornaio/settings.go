@@ -0,0 +1,34 @@
+package main
+
+import (
+	_ "embed"
+
+	"github.com/taldoflemis/box-box/pacchetto"
+)
+
+//go:embed base.yaml
+var baseConfig []byte
+
+type BakeTimeSettings struct {
+	SmallInSeconds  int `mapstructure:"small-in-seconds" validate:"required,min=1"`
+	MediumInSeconds int `mapstructure:"medium-in-seconds" validate:"required,min=1"`
+	LargeInSeconds  int `mapstructure:"large-in-seconds" validate:"required,min=1"`
+}
+
+type FornaioSettings struct {
+	OvenSlots                    int              `mapstructure:"oven-slots" validate:"required,min=1"`
+	BakeTime                     BakeTimeSettings `mapstructure:"bake-time" validate:"required"`
+	VarianceInBakeTimeFactor     float64          `mapstructure:"variance-in-bake-time-factor" validate:"required,min=1,max=2"`
+	ProbabilityOfBurning         float64          `mapstructure:"probability-of-burning" validate:"gte=0,lt=1"`
+	PeriodBetweenBreaksInSeconds int              `mapstructure:"period-between-breaks-in-seconds" validate:"required,min=30"`
+	BreakDurationInSeconds       int              `mapstructure:"break-duration-in-seconds" validate:"required,min=1"`
+	ProbabilityOfLongBreak       float64          `mapstructure:"probability-of-long-break" validate:"gte=0,lte=1"`
+	LongBreakFactor              float64          `mapstructure:"long-break-factor" validate:"required,min=1,max=3"`
+}
+
+type Settings struct {
+	App           pacchetto.AppSettings           `mapstructure:"app" validate:"required"`
+	Fornaio       FornaioSettings                 `mapstructure:"fornaio" validate:"required"`
+	OpenTelemetry pacchetto.OpenTelemetrySettings `mapstructure:"opentelemetry" validate:"required"`
+	GRPCServer    pacchetto.GRPCServerSettings    `mapstructure:"grpc-server" validate:"required"`
+}
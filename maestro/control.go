@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	v1Pb "github.com/taldoflemis/box-box/maestro/v1"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var _ v1Pb.MaestroServiceServer = (*maestroHandlerV1)(nil)
+
+var workerStates = map[workerState]v1Pb.WorkerState{
+	workerIdle:    v1Pb.WorkerState_WorkerIdle,
+	workerCooking: v1Pb.WorkerState_WorkerCooking,
+	workerSmoking: v1Pb.WorkerState_WorkerSmoking,
+}
+
+// GetStatus implements v1.MaestroServiceServer.
+func (m *maestroHandlerV1) GetStatus(ctx context.Context, _ *emptypb.Empty) (*v1Pb.StatusResponse, error) {
+	_, span := tracer.Start(ctx, "maestroHandlerV1.GetStatus")
+	defer span.End()
+
+	return m.statusResponse(), nil
+}
+
+// Pause implements v1.MaestroServiceServer.
+func (m *maestroHandlerV1) Pause(ctx context.Context, _ *emptypb.Empty) (*v1Pb.StatusResponse, error) {
+	ctx, span := tracer.Start(ctx, "maestroHandlerV1.Pause")
+	defer span.End()
+
+	changed := m.schedule.pause()
+	span.SetAttributes(attribute.Bool("maestro.state-changed", changed))
+	if changed {
+		slog.InfoContext(ctx, "Maestro paused, orders already fetched will be finished", slog.Int("in-flight-orders", m.inFlight.len()))
+		m.publishStatus()
+	}
+
+	return m.statusResponse(), nil
+}
+
+// Resume implements v1.MaestroServiceServer.
+func (m *maestroHandlerV1) Resume(ctx context.Context, _ *emptypb.Empty) (*v1Pb.StatusResponse, error) {
+	ctx, span := tracer.Start(ctx, "maestroHandlerV1.Resume")
+	defer span.End()
+
+	changed := m.schedule.resume()
+	span.SetAttributes(attribute.Bool("maestro.state-changed", changed))
+	if changed {
+		slog.InfoContext(ctx, "Maestro resumed")
+		m.publishStatus()
+	}
+
+	return m.statusResponse(), nil
+}
+
+// SkipNextLunch implements v1.MaestroServiceServer.
+func (m *maestroHandlerV1) SkipNextLunch(ctx context.Context, _ *emptypb.Empty) (*v1Pb.StatusResponse, error) {
+	ctx, span := tracer.Start(ctx, "maestroHandlerV1.SkipNextLunch")
+	defer span.End()
+
+	m.schedule.skipLunch()
+	slog.InfoContext(ctx, "Maestro will skip the next lunch")
+	m.publishStatus()
+
+	return m.statusResponse(), nil
+}
+
+// ListInFlightOrders implements v1.MaestroServiceServer.
+func (m *maestroHandlerV1) ListInFlightOrders(ctx context.Context, _ *emptypb.Empty) (*v1Pb.ListInFlightOrdersResponse, error) {
+	_, span := tracer.Start(ctx, "maestroHandlerV1.ListInFlightOrders")
+	defer span.End()
+
+	orders := m.inFlight.list()
+	span.SetAttributes(attribute.Int("maestro.in-flight-orders", len(orders)))
+
+	response := &v1Pb.ListInFlightOrdersResponse{
+		Orders: make([]*v1Pb.InFlightOrder, 0, len(orders)),
+	}
+	for _, order := range orders {
+		response.Orders = append(response.Orders, &v1Pb.InFlightOrder{
+			OrderId:        order.orderID,
+			StreamSequence: order.sequence,
+			Delivery:       order.delivery,
+			FetchedAt:      timestamppb.New(order.fetchedAt),
+			WorkerId:       int32(order.workerID),
+		})
+	}
+
+	return response, nil
+}
+
+// WatchStatus implements v1.MaestroServiceServer.
+func (m *maestroHandlerV1) WatchStatus(_ *emptypb.Empty, stream grpc.ServerStreamingServer[v1Pb.StatusResponse]) error {
+	ctx, span := tracer.Start(stream.Context(), "maestroHandlerV1.WatchStatus")
+	defer span.End()
+
+	slog.DebugContext(ctx, "Started watching maestro status")
+	defer slog.DebugContext(ctx, "Stopped watching maestro status")
+
+	statuses := m.watchers.Watch(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case status := <-statuses:
+			err := stream.Send(status)
+			if err != nil {
+				span.RecordError(err)
+				return err
+			}
+		}
+	}
+}
+
+// publishStatus sends the current status to the WatchStatus streams, on every
+// change of state of the maestro or of a worker.
+func (m *maestroHandlerV1) publishStatus() {
+	m.statusMu.Lock()
+	defer m.statusMu.Unlock()
+
+	m.watchers.Publish(m.statusResponse())
+}
+
+func (m *maestroHandlerV1) statusResponse() *v1Pb.StatusResponse {
+	snapshot := m.schedule.snapshot()
+
+	response := &v1Pb.StatusResponse{
+		State:          snapshot.state,
+		Since:          timestamppb.New(snapshot.since),
+		TimeInState:    durationpb.New(time.Since(snapshot.since)),
+		NextLunch:      timestamppb.New(snapshot.nextLunch),
+		SkipNextLunch:  snapshot.skipNextLunch,
+		Workers:        make([]*v1Pb.WorkerStatus, 0, len(m.workers)),
+		InFlightOrders: int32(m.inFlight.len()),
+	}
+	for _, w := range m.workers {
+		state, orderID, since := w.snapshot()
+		response.Workers = append(response.Workers, &v1Pb.WorkerStatus{
+			Id:      int32(w.id),
+			State:   workerStates[state],
+			OrderId: orderID,
+			Since:   timestamppb.New(since),
+		})
+	}
+
+	return response
+}
@@ -3,13 +3,17 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
+	fornaiov1pb "github.com/taldoflemis/box-box/fornaio/v1"
 	v1Pb "github.com/taldoflemis/box-box/maestro/v1"
 	"github.com/taldoflemis/box-box/pacchetto"
 	"github.com/taldoflemis/box-box/pacchetto/telemetry"
@@ -23,31 +27,40 @@ import (
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
-type Order struct {
-	Size        string    `json:"size"`
-	Toppings    []string  `json:"toppings"`
-	Destination string    `json:"destination"`
-	Username    string    `json:"username"`
-	OrderedAt   time.Time `json:"ordered_at"`
-	OrderID     string    `json:"order_id"`
-	Status      string    `json:"status"` // e.g., "pending", "in_progress", "completed"
-}
+type (
+	Order     = pacchetto.Order
+	OrderItem = pacchetto.OrderItem
+)
+
+const statusDeadLetter = "dead_letter"
 
 type maestroHandlerV1 struct {
 	v1Pb.UnimplementedMaestroServiceServer
-	panettiereClient panettierev1pb.PanettiereServiceClient
-	isSmoking        bool
-	status           string
-	settings         MaestroSettings
-	isLunching       bool
-	subject          string
-	consumer         jetstream.Consumer
-	jsClient         jetstream.JetStream
-	lunchCounter     metric.Int64Counter
-	lunchHistogram   metric.Float64Histogram
-	smokeCounter     metric.Int64Counter
-	smokeHistogram   metric.Float64Histogram
-	healthServer     *health.Server
+	panettiereClient  panettierev1pb.PanettiereServiceClient
+	fornaioClient     fornaiov1pb.FornaioServiceClient
+	settings          MaestroSettings
+	subject           string
+	consumer          jetstream.Consumer
+	stream            jetstream.Stream
+	jsClient          jetstream.JetStream
+	statusKV          jetstream.KeyValue
+	statuses          *pacchetto.OrderStatusPublisher
+	lunchCounter      metric.Int64Counter
+	lunchHistogram    metric.Float64Histogram
+	smokeCounter      metric.Int64Counter
+	smokeHistogram    metric.Float64Histogram
+	burntCounter      metric.Int64Counter
+	deadLetterCounter metric.Int64Counter
+	healthServer      *health.Server
+	workers           []*worker
+	inFlight          *inFlightOrders
+	schedule          *schedule
+	// statusMu keeps the statuses published to watchers in order
+	statusMu sync.Mutex
+	watchers *pacchetto.StatusWatchers[*v1Pb.StatusResponse]
+	// deadLetterRetryDelay is the wait between dead-letter publishes on the
+	// last delivery, replaced in tests
+	deadLetterRetryDelay time.Duration
 }
 
 var (
@@ -57,9 +70,11 @@ var (
 
 func newMaestroHandlerV1(settings MaestroSettings,
 	panettiereClient panettierev1pb.PanettiereServiceClient,
+	fornaioClient fornaiov1pb.FornaioServiceClient,
 	nc *nats.Conn,
 	streamName string,
 	subject string,
+	statusBucket string,
 	healthServer *health.Server,
 ) (*maestroHandlerV1, error) {
 	ctx := context.Background()
@@ -104,6 +119,41 @@ func newMaestroHandlerV1(settings MaestroSettings,
 		return nil, err
 	}
 
+	burntCounter, err := meter.Int64Counter(
+		"maestro.burnt.count",
+		metric.WithDescription("Number of burnt pizzas the maestro has thrown away"),
+		metric.WithUnit("{pizza}"),
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to create burnt counter", slog.Any("err", err))
+		return nil, err
+	}
+
+	deadLetterCounter, err := meter.Int64Counter(
+		"maestro.dead_letter.count",
+		metric.WithDescription("Number of orders the maestro has moved to the dead-letter subject, by reason"),
+		metric.WithUnit("{order}"),
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to create dead-letter counter", slog.Any("err", err))
+		return nil, err
+	}
+
+	busyWorkers, err := meter.Int64UpDownCounter(
+		"maestro.workers.busy",
+		metric.WithDescription("Number of maestro workers cooking or smoking, by worker and state"),
+		metric.WithUnit("{worker}"),
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to create busy workers counter", slog.Any("err", err))
+		return nil, err
+	}
+
+	workers := make([]*worker, settings.Workers)
+	for i := range workers {
+		workers[i] = newWorker(i+1, busyWorkers)
+	}
+
 	js, err := jetstream.New(nc)
 	if err != nil {
 		slog.ErrorContext(ctx, "failed to create jetstream context", slog.Any("err", err))
@@ -116,57 +166,90 @@ func newMaestroHandlerV1(settings MaestroSettings,
 		return nil, err
 	}
 
+	statusKV, err := pacchetto.CreateOrderStatusBucket(ctx, js, statusBucket)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to create order status bucket", slog.Any("err", err))
+		return nil, err
+	}
+
 	c, err := stream.CreateOrUpdateConsumer(ctx, jetstream.ConsumerConfig{
 		Durable:       streamName + "_maestro_new_order_listener_v1",
 		FilterSubject: fmt.Sprintf("%s.waiting_to_cook.*", subject),
 		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       retryBackoff(settings, 1),
+		MaxDeliver:    settings.MaxDeliveries,
+		BackOff:       consumerBackOff(settings),
 	})
 	if err != nil {
 		slog.ErrorContext(ctx, "failed to create consumer", slog.Any("err", err))
 		return nil, err
 	}
 
-	return &maestroHandlerV1{
-		panettiereClient: panettiereClient,
-		settings:         settings,
-		consumer:         c,
-		subject:          subject,
-		jsClient:         js,
-		lunchCounter:     lunchCounter,
-		lunchHistogram:   lunchHistogram,
-		smokeCounter:     smokeCounter,
-		smokeHistogram:   smokeHistogram,
-		healthServer:     healthServer,
-	}, nil
-}
-
-var _ v1Pb.MaestroServiceServer = (*maestroHandlerV1)(nil)
-
-// SayHello implements v1.MaestroServiceServer.
-func (m *maestroHandlerV1) SayHello(ctx context.Context, req *v1Pb.HelloRequest) (*v1Pb.HelloReply, error) {
-	ctx, span := tracer.Start(ctx, "maestroHandlerV1.SayHello")
-	defer span.End()
-
-	slog.InfoContext(ctx, "Received request", slog.Any("req", req))
+	m := &maestroHandlerV1{
+		panettiereClient:  panettiereClient,
+		fornaioClient:     fornaioClient,
+		settings:          settings,
+		consumer:          c,
+		stream:            stream,
+		subject:           subject,
+		jsClient:          js,
+		statusKV:          statusKV,
+		statuses:          pacchetto.NewOrderStatusPublisher(js, statusKV, subject),
+		lunchCounter:      lunchCounter,
+		lunchHistogram:    lunchHistogram,
+		smokeCounter:      smokeCounter,
+		smokeHistogram:    smokeHistogram,
+		burntCounter:      burntCounter,
+		deadLetterCounter: deadLetterCounter,
+		healthServer:      healthServer,
+		workers:           workers,
+		inFlight:          newInFlightOrders(),
+		schedule:          newSchedule(time.Duration(settings.PeriodBetweenLunchInSeconds) * time.Second),
+	}
+	m.watchers = pacchetto.NewStatusWatchers(m.statusResponse())
 
-	return &v1Pb.HelloReply{
-		Message: "Hello " + req.Name,
-	}, nil
+	return m, nil
 }
 
 func (m *maestroHandlerV1) startTurn(ctx context.Context) {
-	slog.Info("Maestro is starting his turn")
+	slog.Info("Maestro is starting his turn", slog.Int("workers", len(m.workers)))
+
+	// Unbuffered, so orders are only taken from a batch when a worker is free
+	orders := make(chan *inFlightOrder)
+	var workersDone sync.WaitGroup
+	for _, w := range m.workers {
+		workersDone.Go(func() {
+			m.work(w, orders)
+		})
+	}
+	defer func() {
+		close(orders)
+		workersDone.Wait()
+	}()
 
-	lunchTicker := time.NewTicker(time.Duration(m.settings.PeriodBetweenLunchInSeconds) * time.Second)
+	lunchPeriod := time.Duration(m.settings.PeriodBetweenLunchInSeconds) * time.Second
+	lunchTicker := time.NewTicker(lunchPeriod)
 	defer lunchTicker.Stop()
-	hasTicketed := false
+	m.schedule.planLunch()
+	m.publishStatus()
 
 	go func() {
-		<-lunchTicker.C
-		hasTicketed = true
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-lunchTicker.C:
+				if m.schedule.lunchTime() {
+					slog.Info("Maestro skipped lunch")
+					m.publishStatus()
+				}
+			}
+		}
 	}()
 
 	for {
+		m.schedule.waitWhilePaused(ctx)
+
 		select {
 		case <-ctx.Done():
 			slog.Info("Maestro ended his turn")
@@ -175,33 +258,24 @@ func (m *maestroHandlerV1) startTurn(ctx context.Context) {
 			ctx := context.Background()
 			slog.DebugContext(ctx, "Starting internal loop")
 
-			orders, err := m.getNewBatchMessages(ctx)
+			msgs, err := m.getNewBatchMessages(ctx)
 			if err != nil {
 				continue
 			}
 
-			for order := range orders {
-				err = order.InProgress()
-				if err != nil {
-					slog.ErrorContext(ctx, "failed to set message in progress", slog.Any("err", err))
-					continue
-				}
-				m.processNewOrder(ctx, order)
+			for order := range m.keepBatchInProgress(ctx, msgs) {
+				m.inFlight.add(order)
+				orders <- order
 			}
 
-			if !hasTicketed {
+			if !m.schedule.takeDueLunch() {
 				continue
 			}
 
+			// The whole kitchen stops for lunch, orders already handed out are finished first
+			m.inFlight.wait()
 			m.lunch(ctx)
-			hasTicketed = false
-			lunchTicker.Reset(time.Duration(m.settings.PeriodBetweenLunchInSeconds) * time.Second)
-
-			// We garantee that this goroutine will run only once
-			go func() {
-				<-lunchTicker.C
-				hasTicketed = true
-			}()
+			lunchTicker.Reset(lunchPeriod)
 		}
 	}
 }
@@ -233,8 +307,8 @@ func (m *maestroHandlerV1) lunch(ctx context.Context) {
 	defer span.End()
 
 	m.healthServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
-	m.isLunching = true
-	m.status = "lunching"
+	m.schedule.startLunch()
+	m.publishStatus()
 
 	slog.InfoContext(ctx, "Maestro is having lunch", slog.Int("lunch-duration-in-seconds", m.settings.LunchDurationInSeconds))
 	time.Sleep(time.Duration(m.settings.LunchDurationInSeconds) * time.Second)
@@ -244,13 +318,17 @@ func (m *maestroHandlerV1) lunch(ctx context.Context) {
 	m.lunchHistogram.Record(ctx, float64(m.settings.LunchDurationInSeconds))
 
 	m.healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
-	m.isLunching = false
-	m.status = "idle"
+	m.schedule.endLunch()
+	m.publishStatus()
 }
 
-func (m *maestroHandlerV1) processNewOrder(ctx context.Context, msg jetstream.Msg) {
+// processNewOrder cooks the order of msg on worker w and reports whether it
+// was cooked and handed on, which is when the worker goes for a smoke.
+func (m *maestroHandlerV1) processNewOrder(ctx context.Context, w *worker, msg jetstream.Msg) (Order, bool) {
 	ctx = telemetry.GetContextFromJetstreamMsg(ctx, msg)
-	ctx, span := tracer.Start(ctx, "maestroHandlerV1.processNewOrder")
+	ctx, span := tracer.Start(ctx, "maestroHandlerV1.processNewOrder", trace.WithAttributes(
+		attribute.Int("maestro.worker.id", w.id),
+	))
 	defer span.End()
 
 	var order Order
@@ -260,34 +338,78 @@ func (m *maestroHandlerV1) processNewOrder(ctx context.Context, msg jetstream.Ms
 		slog.ErrorContext(ctx, "failed to unmarshal order from NATS message", slog.Any("err", err))
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
-		return
+
+		// Redelivering a payload that cannot be decoded will not help
+		err = m.sendToDeadLetter(ctx, msg, nil, pacchetto.DeadLetterReasonMalformed, err)
+		if err != nil {
+			return Order{}, false
+		}
+		m.term(ctx, msg)
+		return Order{}, false
 	}
 
 	slog.DebugContext(ctx, "Deserialized order", slog.Any("order", order))
 
+	order.NormalizeItems()
+
 	span.SetAttributes(
 		attribute.String("box-box.orderid", order.OrderID),
-		attribute.String("order.size", order.Size),
+		attribute.Int("order.items", len(order.Items)),
+		attribute.Int("order.pizzas", order.Pizzas()),
 		attribute.String("order.destination", order.Destination),
 		attribute.String("order.username", order.Username),
-		attribute.StringSlice("order.toppings", order.Toppings),
 	)
 
-	m.status = fmt.Sprintf("processing order %s", order.OrderID)
+	w.setState(ctx, workerCooking, order.OrderID)
+	m.publishStatus()
 
-	doughResponse, err := m.requestDough(ctx, order)
+	doughRequests, err := newDoughRequests(order)
 	if err != nil {
-		slog.ErrorContext(ctx, "failed to request dough", slog.String("order-id", order.OrderID), slog.Any("err", err))
-		return
+		// Retrying cannot fix the order, cooking a different pizza would be worse
+		rejectErr := m.sendToRejected(ctx, order, err)
+		if rejectErr != nil {
+			m.retry(ctx, msg, &order, rejectErr)
+			return order, false
+		}
+
+		m.term(ctx, msg)
+		return order, false
 	}
 
-	slog.DebugContext(ctx, "Dough response", slog.Any("doughResponse", doughResponse))
+	cancelled, err := m.isCancelled(ctx, order.OrderID)
+	if err != nil {
+		m.retry(ctx, msg, &order, err)
+		return order, false
+	}
 
-	slog.InfoContext(ctx, "Order processed successfully", slog.String("order-id", order.OrderID))
+	if cancelled {
+		slog.InfoContext(ctx, "Order was cancelled before cooking, dropping it", slog.String("order-id", order.OrderID))
+		span.AddEvent("order cancelled before cooking")
+
+		m.term(ctx, msg)
+		return order, false
+	}
 
-	err = m.sendToDeliveryQueue(ctx, order)
+	cancelled, err = m.prepareItems(ctx, &order, doughRequests)
+	if err == nil && !cancelled {
+		// Checked right before the order moves on, so an order cancelled while
+		// its last pizza was baking is not sent to delivery
+		cancelled, err = m.isCancelled(ctx, order.OrderID)
+	}
 	if err != nil {
-		return
+		m.retry(ctx, msg, &order, err)
+		return order, false
+	}
+
+	if cancelled {
+		err = m.sendToCancelledAfterPrep(ctx, order)
+	} else {
+		slog.InfoContext(ctx, "Order processed successfully", slog.String("order-id", order.OrderID))
+		err = m.sendToDeliveryQueue(ctx, order)
+	}
+	if err != nil {
+		m.retry(ctx, msg, &order, err)
+		return order, false
 	}
 
 	slog.DebugContext(ctx, "Acknowledging message")
@@ -297,41 +419,342 @@ func (m *maestroHandlerV1) processNewOrder(ctx context.Context, msg jetstream.Ms
 		slog.ErrorContext(ctx, "Failed to acknowledge message", slog.Any("err", err))
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
-		return
+		return order, false
 	}
 
 	slog.DebugContext(ctx, "Acknowledged message")
 
-	m.smoke(ctx, order)
+	return order, true
 }
 
-func (m *maestroHandlerV1) sendToDeliveryQueue(ctx context.Context, order Order) error {
-	ctx, span := tracer.Start(ctx, "maestroHandlerV1.sendToDeliveryQueue", trace.WithAttributes(
-		attribute.String("box-box.orderid", order.OrderID),
+// retry leaves the order to be redelivered by the consumer after its BackOff,
+// or moves it to the dead-letter subject on the last delivery of MaxDeliver.
+func (m *maestroHandlerV1) retry(ctx context.Context, msg jetstream.Msg, order *Order, cause error) {
+	span := trace.SpanFromContext(ctx)
+
+	deliveries := deliveryCount(ctx, msg)
+	if deliveries < m.settings.MaxDeliveries {
+		slog.WarnContext(ctx, "Failed to process order, retrying later",
+			slog.String("order-id", order.OrderID),
+			slog.Int("delivery", deliveries),
+			slog.Duration("delay", retryBackoff(m.settings, deliveries)),
+			slog.Any("err", cause))
+		span.AddEvent("order retried", trace.WithAttributes(attribute.Int("maestro.delivery", deliveries)))
+
+		// Not naked: the server ignores BackOff for naks, the ack wait running
+		// out once the worker stops the heartbeat is what waits the backoff
+		return
+	}
+
+	// The consumer does not redeliver after MaxDeliver, so the publish is
+	// retried here until the order is safe on the dead-letter subject
+	for {
+		err := m.sendToDeadLetter(ctx, msg, order, pacchetto.DeadLetterReasonMaxDeliveries, cause)
+		if err == nil {
+			m.term(ctx, msg)
+			return
+		}
+
+		slog.WarnContext(ctx, "Failed to dead-letter order, retrying",
+			slog.String("order-id", order.OrderID),
+			slog.Duration("delay", m.deadLetterRetryDelay),
+			slog.Any("err", err))
+
+		select {
+		case <-ctx.Done():
+			span.RecordError(ctx.Err())
+			return
+		case <-time.After(m.deadLetterRetryDelay):
+		}
+	}
+}
+
+// retryBackoff is the delay before the next delivery of a message delivered
+// deliveries times, doubling from RetryBackoffBaseInSeconds up to RetryBackoffMaxInSeconds.
+func retryBackoff(settings MaestroSettings, deliveries int) time.Duration {
+	base := time.Duration(settings.RetryBackoffBaseInSeconds) * time.Second
+	maxDelay := time.Duration(settings.RetryBackoffMaxInSeconds) * time.Second
+
+	delay := base
+	for range deliveries - 1 {
+		delay *= 2
+		if delay >= maxDelay {
+			return maxDelay
+		}
+	}
+	return min(delay, maxDelay)
+}
+
+// consumerBackOff is the BackOff of the consumer, the delay before each
+// redelivery up to MaxDeliveries.
+func consumerBackOff(settings MaestroSettings) []time.Duration {
+	backOff := make([]time.Duration, 0, settings.MaxDeliveries-1)
+	for deliveries := 1; deliveries < settings.MaxDeliveries; deliveries++ {
+		backOff = append(backOff, retryBackoff(settings, deliveries))
+	}
+	return backOff
+}
+
+// deliveryCount is how often the message was delivered, including this delivery.
+func deliveryCount(ctx context.Context, msg jetstream.Msg) int {
+	meta, err := msg.Metadata()
+	if err != nil {
+		slog.WarnContext(ctx, "failed to read message metadata", slog.Any("err", err))
+		return 1
+	}
+	return int(meta.NumDelivered)
+}
+
+// sendToDeadLetter republishes the message to orders.dead_letter.{order_id}
+// with the reason it failed in its headers. Orders that could be decoded are
+// marked dead_letter in the status bucket too, malformed payloads are kept as
+// they are.
+func (m *maestroHandlerV1) sendToDeadLetter(ctx context.Context, msg jetstream.Msg, order *Order, reason string, cause error) error {
+	ctx, span := tracer.Start(ctx, "maestroHandlerV1.sendToDeadLetter", trace.WithAttributes(
+		attribute.String("maestro.dead-letter.reason", reason),
 	))
 	defer span.End()
 
-	slog.DebugContext(ctx, "Sending order to delivery queue", slog.String("order-id", order.OrderID))
+	deliveries := deliveryCount(ctx, msg)
+
+	orderID := "unknown"
+	data := msg.Data()
+	if order != nil {
+		orderID = order.OrderID
+		span.SetAttributes(attribute.String("box-box.orderid", orderID))
 
-	msg := &nats.Msg{
-		Subject: fmt.Sprintf("%s.waiting_delivery.%s", m.subject, order.OrderID),
+		dead := *order
+		dead.Status = statusDeadLetter
+		dead.Reason = cause.Error()
+		if dead.Timestamps == nil {
+			dead.Timestamps = make(map[string]time.Time)
+		}
+		dead.Timestamps[statusDeadLetter] = time.Now()
+
+		var err error
+		data, err = json.Marshal(dead)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to marshal order to json", slog.Any("err", err))
+			return err
+		}
+	} else if meta, err := msg.Metadata(); err == nil {
+		// Malformed payloads have no order ID, the stream sequence still tells them apart
+		orderID = fmt.Sprintf("seq-%d", meta.Sequence.Stream)
+	}
+
+	deadMsg := &nats.Msg{
+		Subject: fmt.Sprintf("%s.%s.%s", m.subject, statusDeadLetter, orderID),
 		Header:  nats.Header{},
+		Data:    data,
+	}
+	deadMsg.Header.Set(pacchetto.DeadLetterErrorHeader, cause.Error())
+	deadMsg.Header.Set(pacchetto.DeadLetterReasonHeader, reason)
+	deadMsg.Header.Set(pacchetto.DeadLetterSubjectHeader, msg.Subject())
+	deadMsg.Header.Set(pacchetto.DeadLetterDeliveriesHeader, strconv.Itoa(deliveries))
+	deadMsg.Header.Set(pacchetto.DeadLetterFailedAtHeader, time.Now().UTC().Format(time.RFC3339))
+	if meta, err := msg.Metadata(); err == nil {
+		deadMsg.Header.Set(pacchetto.DeadLetterSequenceHeader, strconv.FormatUint(meta.Sequence.Stream, 10))
+		deadMsg.Header.Set(pacchetto.DeadLetterConsumerHeader, meta.Consumer)
+	}
+	telemetry.InjectContextToNatsMsg(ctx, deadMsg)
+
+	_, err := m.jsClient.PublishMsg(ctx, deadMsg)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to publish order to dead-letter subject", slog.String("order-id", orderID), slog.Any("err", err))
+		span.SetStatus(codes.Error, "failed to publish order to dead-letter subject")
+		span.RecordError(err)
+		return err
+	}
+
+	m.deadLetterCounter.Add(ctx, 1, metric.WithAttributes(attribute.String("maestro.dead-letter.reason", reason)))
+	slog.ErrorContext(ctx, "Order moved to the dead-letter subject",
+		slog.String("order-id", orderID),
+		slog.String("reason", reason),
+		slog.Int("deliveries", deliveries),
+		slog.Any("err", cause))
+
+	if order != nil {
+		_, err = m.statusKV.Put(ctx, order.OrderID, data)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to update order status bucket", slog.String("order-id", order.OrderID), slog.Any("err", err))
+			span.RecordError(err)
+		}
+	}
+
+	return nil
+}
+
+func (m *maestroHandlerV1) term(ctx context.Context, msg jetstream.Msg) {
+	err := msg.Term()
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to terminate message", slog.Any("err", err))
+		span := trace.SpanFromContext(ctx)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// prepareItems makes and bakes every pizza of the order, item by item, and
+// resumes from the progress saved by a previous attempt at the same order. The
+// customer may cancel while the pizzas are being made: the work is lost but the
+// order must not reach the delivery queue, so cancellations are checked between
+// items. The caller checks after the last one, right before the order moves on.
+func (m *maestroHandlerV1) prepareItems(ctx context.Context, order *Order, doughRequests []*panettierev1pb.DoughRequest) (bool, error) {
+	ctx, span := tracer.Start(ctx, "maestroHandlerV1.prepareItems", trace.WithAttributes(
+		attribute.String("box-box.orderid", order.OrderID),
+	))
+	defer span.End()
+
+	m.resumeProgress(ctx, order)
+
+	for i := range order.Items {
+		item := &order.Items[i]
+
+		for item.Prepared < item.Quantity {
+			err := m.preparePizza(ctx, doughRequests[i])
+			if err != nil {
+				slog.ErrorContext(ctx, "failed to prepare pizza", slog.String("order-id", order.OrderID), slog.Int("item", i), slog.Any("err", err))
+				span.RecordError(err)
+				span.SetStatus(codes.Error, "failed to prepare pizza")
+				return false, err
+			}
+
+			item.Prepared++
+			m.saveProgress(ctx, *order)
+		}
+
+		span.AddEvent("item prepared", trace.WithAttributes(
+			attribute.Int("order.item", i),
+			attribute.Int("order.item.quantity", item.Quantity),
+		))
+
+		if i == len(order.Items)-1 {
+			break
+		}
+
+		cancelled, err := m.isCancelled(ctx, order.OrderID)
+		if err != nil || cancelled {
+			return cancelled, err
+		}
+	}
+
+	return false, nil
+}
+
+// preparePizza asks panettiere for the dough of a pizza and fornaio to bake it.
+// Burnt pizzas are thrown away and made again from a new dough, up to
+// MaxBakeAttempts times.
+func (m *maestroHandlerV1) preparePizza(ctx context.Context, doughRequest *panettierev1pb.DoughRequest) error {
+	for attempt := 1; ; attempt++ {
+		doughResponse, err := m.requestDough(ctx, doughRequest)
+		if err != nil {
+			return err
+		}
+
+		bakeResponse, err := m.requestBake(ctx, &fornaiov1pb.BakeRequest{
+			OrderId: doughRequest.OrderId,
+			Size:    doughRequest.Size,
+			Dough:   doughResponse.Content,
+		})
+		if err != nil {
+			return err
+		}
+
+		if !bakeResponse.Burnt {
+			return nil
+		}
+
+		m.burntCounter.Add(ctx, 1)
+		if attempt >= m.settings.MaxBakeAttempts {
+			return fmt.Errorf("pizza burnt %d times in a row", attempt)
+		}
+
+		slog.WarnContext(ctx, "Pizza came out burnt, making it again", slog.String("order-id", doughRequest.OrderId), slog.Int("attempt", attempt))
+	}
+}
+
+// resumeProgress copies the pizzas a previous attempt at the order already
+// made, as saved in the status bucket, so a redelivered order is not cooked twice.
+func (m *maestroHandlerV1) resumeProgress(ctx context.Context, order *Order) {
+	entry, err := m.statusKV.Get(ctx, order.OrderID)
+	if err != nil {
+		if !errors.Is(err, jetstream.ErrKeyNotFound) {
+			slog.WarnContext(ctx, "failed to read order progress, starting over", slog.String("order-id", order.OrderID), slog.Any("err", err))
+		}
+		return
+	}
+
+	var saved Order
+	err = json.Unmarshal(entry.Value(), &saved)
+	if err != nil || len(saved.Items) != len(order.Items) {
+		return
+	}
+
+	for i := range order.Items {
+		order.Items[i].Prepared = min(saved.Items[i].Prepared, order.Items[i].Quantity)
+	}
+}
+
+// saveProgress writes the pizzas baked so far to the status bucket. The update
+// only applies while the order still has the same status there, so it never
+// hides a cancellation published meanwhile.
+func (m *maestroHandlerV1) saveProgress(ctx context.Context, order Order) {
+	entry, err := m.statusKV.Get(ctx, order.OrderID)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to read order status to save progress", slog.String("order-id", order.OrderID), slog.Any("err", err))
+		return
 	}
 
-	order.Status = "waiting_delivery"
+	var current Order
+	err = json.Unmarshal(entry.Value(), &current)
+	if err != nil || current.Status != order.Status {
+		return
+	}
 
-	telemetry.InjectContextToNatsMsg(ctx, msg)
 	data, err := json.Marshal(order)
 	if err != nil {
 		slog.ErrorContext(ctx, "failed to marshal order to json", slog.Any("err", err))
-		span.SetStatus(codes.Error, "failed to marshal order")
+		return
+	}
+
+	_, err = m.statusKV.Update(ctx, order.OrderID, data, entry.Revision())
+	if err != nil {
+		slog.WarnContext(ctx, "failed to save order progress", slog.String("order-id", order.OrderID), slog.Any("err", err))
+	}
+}
+
+// isCancelled reports whether the customer published a cancellation event for the order.
+func (m *maestroHandlerV1) isCancelled(ctx context.Context, orderID string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "maestroHandlerV1.isCancelled", trace.WithAttributes(
+		attribute.String("box-box.orderid", orderID),
+	))
+	defer span.End()
+
+	_, err := m.stream.GetLastMsgForSubject(ctx, fmt.Sprintf("%s.cancelled.%s", m.subject, orderID))
+	if errors.Is(err, jetstream.ErrMsgNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to look up order cancellation", slog.String("order-id", orderID), slog.Any("err", err))
+		span.SetStatus(codes.Error, "failed to look up order cancellation")
 		span.RecordError(err)
-		return err
+		return false, err
 	}
 
-	msg.Data = data
+	span.SetAttributes(attribute.Bool("maestro.order-cancelled", true))
+
+	return true, nil
+}
 
-	_, err = m.jsClient.PublishMsg(ctx, msg)
+func (m *maestroHandlerV1) sendToDeliveryQueue(ctx context.Context, order Order) error {
+	ctx, span := tracer.Start(ctx, "maestroHandlerV1.sendToDeliveryQueue", trace.WithAttributes(
+		attribute.String("box-box.orderid", order.OrderID),
+	))
+	defer span.End()
+
+	slog.DebugContext(ctx, "Sending order to delivery queue", slog.String("order-id", order.OrderID))
+
+	err := m.statuses.Publish(ctx, order, "waiting_delivery")
 	if err != nil {
 		slog.ErrorContext(ctx, "failed to publish order to delivery queue", slog.Any("err", err))
 		span.SetStatus(codes.Error, "failed to publish order to delivery queue")
@@ -344,16 +767,57 @@ func (m *maestroHandlerV1) sendToDeliveryQueue(ctx context.Context, order Order)
 	return nil
 }
 
-func (m *maestroHandlerV1) smoke(ctx context.Context, order Order) {
+func (m *maestroHandlerV1) sendToRejected(ctx context.Context, order Order, reason error) error {
+	ctx, span := tracer.Start(ctx, "maestroHandlerV1.sendToRejected", trace.WithAttributes(
+		attribute.String("box-box.orderid", order.OrderID),
+	))
+	defer span.End()
+
+	slog.WarnContext(ctx, "Rejecting malformed order", slog.String("order-id", order.OrderID), slog.Any("reason", reason))
+	span.RecordError(reason)
+
+	order.Reason = reason.Error()
+	err := m.statuses.Publish(ctx, order, "rejected")
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to publish rejected order", slog.Any("err", err))
+		span.SetStatus(codes.Error, "failed to publish rejected order")
+		span.RecordError(err)
+		return err
+	}
+
+	return nil
+}
+
+func (m *maestroHandlerV1) sendToCancelledAfterPrep(ctx context.Context, order Order) error {
+	ctx, span := tracer.Start(ctx, "maestroHandlerV1.sendToCancelledAfterPrep", trace.WithAttributes(
+		attribute.String("box-box.orderid", order.OrderID),
+	))
+	defer span.End()
+
+	slog.InfoContext(ctx, "Order was cancelled while the dough was being made", slog.String("order-id", order.OrderID))
+
+	err := m.statuses.Publish(ctx, order, "cancelled_after_prep")
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to publish order cancelled after preparation", slog.Any("err", err))
+		span.SetStatus(codes.Error, "failed to publish order cancelled after preparation")
+		span.RecordError(err)
+		return err
+	}
+
+	return nil
+}
+
+func (m *maestroHandlerV1) smoke(ctx context.Context, w *worker, order Order) {
 	ctx, span := tracer.Start(ctx, "maestroHandlerV1.smoke", trace.WithAttributes(
 		attribute.String("box-box.orderid", order.OrderID),
+		attribute.Int("maestro.worker.id", w.id),
 	))
 	defer span.End()
 
-	slog.DebugContext(ctx, "Starting smoking after order", slog.String("order-id", order.OrderID))
+	slog.DebugContext(ctx, "Starting smoking after order", slog.String("order-id", order.OrderID), slog.Int("worker-id", w.id))
 
-	m.isSmoking = true
-	m.status = "smoking"
+	w.setState(ctx, workerSmoking, order.OrderID)
+	m.publishStatus()
 
 	sleepDuration := time.Duration(m.settings.SmokingDurationInSeconds) * time.Second
 
@@ -365,40 +829,89 @@ func (m *maestroHandlerV1) smoke(ctx context.Context, order Order) {
 	}
 
 	time.Sleep(sleepDuration)
-	m.smokeCounter.Add(ctx, 1)
-	m.smokeHistogram.Record(ctx, sleepDuration.Seconds())
+	workerAttribute := metric.WithAttributes(attribute.Int("maestro.worker.id", w.id))
+	m.smokeCounter.Add(ctx, 1, workerAttribute)
+	m.smokeHistogram.Record(ctx, sleepDuration.Seconds(), workerAttribute)
 
-	m.isSmoking = false
-	m.status = "idle"
-
-	slog.InfoContext(ctx, "Finished smoking after order", slog.String("order-id", order.OrderID))
+	slog.InfoContext(ctx, "Finished smoking after order", slog.String("order-id", order.OrderID), slog.Int("worker-id", w.id))
 }
 
-func (m *maestroHandlerV1) requestDough(ctx context.Context, order Order) (*panettierev1pb.DoughResponse, error) {
+func (m *maestroHandlerV1) requestDough(ctx context.Context, doughRequest *panettierev1pb.DoughRequest) (*panettierev1pb.DoughResponse, error) {
 	ctx, span := tracer.Start(ctx, "maestroHandlerV1.requestDough", trace.WithAttributes(
-		attribute.String("box-box.orderid", order.OrderID),
+		attribute.String("box-box.orderid", doughRequest.OrderId),
+		attribute.String("dough.size", doughRequest.Size.String()),
+		attribute.String("dough.border", doughRequest.Border.String()),
 	))
 	defer span.End()
 
-	slog.DebugContext(ctx, "Requesting dough from panettiere", slog.Any("order", order))
-
-	doughRequest := &panettierev1pb.DoughRequest{
-		OrderId: order.OrderID,
-		Border:  panettierev1pb.BorderKind_NoBorder,
-		Size:    panettierev1pb.PizzaSize_Small,
-	}
+	slog.DebugContext(ctx, "Requesting dough from panettiere", slog.Any("dough-request", doughRequest))
 
 	doughResponse, err := m.panettiereClient.MakeDough(ctx, doughRequest)
 	if err != nil {
-		slog.ErrorContext(ctx, "failed to make dough", slog.String("order-id", order.OrderID), slog.Any("err", err))
+		slog.ErrorContext(ctx, "failed to make dough", slog.String("order-id", doughRequest.OrderId), slog.Any("err", err))
 		span.RecordError(err)
 		return nil, err
 	}
 
-	slog.InfoContext(ctx, "Received dough from panettiere", slog.String("order-id", order.OrderID), slog.String("dough-content", doughResponse.Content))
+	slog.InfoContext(ctx, "Received dough from panettiere", slog.String("order-id", doughRequest.OrderId), slog.String("dough-content", doughResponse.Content))
 	return doughResponse, nil
 }
 
+func (m *maestroHandlerV1) requestBake(ctx context.Context, bakeRequest *fornaiov1pb.BakeRequest) (*fornaiov1pb.BakeResponse, error) {
+	ctx, span := tracer.Start(ctx, "maestroHandlerV1.requestBake", trace.WithAttributes(
+		attribute.String("box-box.orderid", bakeRequest.OrderId),
+		attribute.String("bake.size", bakeRequest.Size.String()),
+	))
+	defer span.End()
+
+	slog.DebugContext(ctx, "Requesting bake from fornaio", slog.Any("bake-request", bakeRequest))
+
+	bakeResponse, err := m.fornaioClient.Bake(ctx, bakeRequest)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to bake", slog.String("order-id", bakeRequest.OrderId), slog.Any("err", err))
+		span.RecordError(err)
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Bool("bake.burnt", bakeResponse.Burnt))
+
+	slog.InfoContext(ctx, "Received pizza from fornaio", slog.String("order-id", bakeRequest.OrderId), slog.String("pizza-content", bakeResponse.Content), slog.Bool("burnt", bakeResponse.Burnt))
+	return bakeResponse, nil
+}
+
+// newDoughRequests maps every item of the order to the dough panettiere has to
+// make, failing on sizes and borders the kitchen does not know.
+func newDoughRequests(order Order) ([]*panettierev1pb.DoughRequest, error) {
+	if len(order.Items) == 0 {
+		return nil, errors.New("order has no items")
+	}
+
+	requests := make([]*panettierev1pb.DoughRequest, 0, len(order.Items))
+	for i, item := range order.Items {
+		if item.Quantity < 1 {
+			return nil, fmt.Errorf("items[%d]: invalid quantity: %d", i, item.Quantity)
+		}
+
+		size, err := parsePizzaSize(item.Size)
+		if err != nil {
+			return nil, fmt.Errorf("items[%d]: %w", i, err)
+		}
+
+		border, err := parseBorderKind(item.Border)
+		if err != nil {
+			return nil, fmt.Errorf("items[%d]: %w", i, err)
+		}
+
+		requests = append(requests, &panettierev1pb.DoughRequest{
+			OrderId: order.OrderID,
+			Border:  border,
+			Size:    size,
+		})
+	}
+
+	return requests, nil
+}
+
 func parsePizzaSize(size string) (panettierev1pb.PizzaSize, error) {
 	switch strings.ToLower(size) {
 	case "small":
@@ -411,3 +924,19 @@ func parsePizzaSize(size string) (panettierev1pb.PizzaSize, error) {
 		return panettierev1pb.PizzaSize_Small, fmt.Errorf("unknown pizza size: %s", size)
 	}
 }
+
+// parseBorderKind accepts an empty border for orders placed before borders existed.
+func parseBorderKind(border string) (panettierev1pb.BorderKind, error) {
+	switch strings.ToLower(border) {
+	case "", "none":
+		return panettierev1pb.BorderKind_NoBorder, nil
+	case "cream_cheese":
+		return panettierev1pb.BorderKind_CreamCheese, nil
+	case "cheddar":
+		return panettierev1pb.BorderKind_Cheddar, nil
+	case "chocolate":
+		return panettierev1pb.BorderKind_Chocolate, nil
+	default:
+		return panettierev1pb.BorderKind_NoBorder, fmt.Errorf("unknown border: %s", border)
+	}
+}
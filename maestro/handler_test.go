@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	panettierev1pb "github.com/taldoflemis/box-box/panettiere/v1"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+func TestNewDoughRequests(t *testing.T) {
+	type dough struct {
+		size   panettierev1pb.PizzaSize
+		border panettierev1pb.BorderKind
+	}
+
+	tests := []struct {
+		name      string
+		order     Order
+		wantDough []dough
+		wantErr   bool
+	}{
+		{
+			name: "one dough request per item",
+			order: Order{Items: []OrderItem{
+				{Size: "large", Border: "chocolate", Quantity: 3},
+				{Size: "small", Border: "none", Quantity: 1},
+			}},
+			wantDough: []dough{
+				{panettierev1pb.PizzaSize_Large, panettierev1pb.BorderKind_Chocolate},
+				{panettierev1pb.PizzaSize_Small, panettierev1pb.BorderKind_NoBorder},
+			},
+		},
+		{
+			name:      "single pizza orders from before line items",
+			order:     Order{Size: "medium"},
+			wantDough: []dough{{panettierev1pb.PizzaSize_Medium, panettierev1pb.BorderKind_NoBorder}},
+		},
+		{
+			name:    "unknown size",
+			order:   Order{Items: []OrderItem{{Size: "huge", Border: "cheddar", Quantity: 1}}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown border",
+			order:   Order{Items: []OrderItem{{Size: "small", Border: "catupiry", Quantity: 1}}},
+			wantErr: true,
+		},
+		{
+			name:    "no quantity",
+			order:   Order{Items: []OrderItem{{Size: "small", Border: "none"}}},
+			wantErr: true,
+		},
+		{
+			name:    "no items",
+			order:   Order{},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			tt.order.NormalizeItems()
+
+			// Act
+			reqs, err := newDoughRequests(tt.order)
+
+			// Assert
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			got := make([]dough, 0, len(reqs))
+			for _, req := range reqs {
+				got = append(got, dough{req.Size, req.Border})
+			}
+			assert.Equal(t, tt.wantDough, got)
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	settings := MaestroSettings{RetryBackoffBaseInSeconds: 5, RetryBackoffMaxInSeconds: 60}
+
+	tests := []struct {
+		deliveries int
+		want       time.Duration
+	}{
+		{deliveries: 1, want: 5 * time.Second},
+		{deliveries: 2, want: 10 * time.Second},
+		{deliveries: 4, want: 40 * time.Second},
+		{deliveries: 5, want: 60 * time.Second},
+		{deliveries: 50, want: 60 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.deliveries), func(t *testing.T) {
+			// Act
+			got := retryBackoff(settings, tt.deliveries)
+
+			// Assert
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// fakeMsg is a message delivered deliveries times, recording how it was settled.
+type fakeMsg struct {
+	jetstream.Msg
+	deliveries uint64
+	naked      bool
+	termed     bool
+}
+
+func (m *fakeMsg) Metadata() (*jetstream.MsgMetadata, error) {
+	return &jetstream.MsgMetadata{
+		Sequence:     jetstream.SequencePair{Stream: 42},
+		NumDelivered: m.deliveries,
+		Consumer:     "maestro",
+	}, nil
+}
+
+func (m *fakeMsg) Data() []byte    { return []byte(`{"order_id":"order-1"}`) }
+func (m *fakeMsg) Subject() string { return "orders.waiting_to_cook.order-1" }
+
+func (m *fakeMsg) Nak() error {
+	m.naked = true
+	return nil
+}
+
+func (m *fakeMsg) NakWithDelay(time.Duration) error {
+	m.naked = true
+	return nil
+}
+
+func (m *fakeMsg) Term() error {
+	m.termed = true
+	return nil
+}
+
+// fakeJetStream records the messages published, failing the first failures
+// of them with publishErr.
+type fakeJetStream struct {
+	jetstream.JetStream
+	publishErr error
+	failures   int
+	published  []*nats.Msg
+}
+
+func (js *fakeJetStream) PublishMsg(_ context.Context, msg *nats.Msg, _ ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	js.published = append(js.published, msg)
+	if len(js.published) <= js.failures {
+		return nil, js.publishErr
+	}
+	return &jetstream.PubAck{}, nil
+}
+
+// fakeKeyValue accepts every status written to the bucket.
+type fakeKeyValue struct {
+	jetstream.KeyValue
+}
+
+func (kv *fakeKeyValue) Put(context.Context, string, []byte) (uint64, error) {
+	return 1, nil
+}
+
+func TestConsumerBackOff(t *testing.T) {
+	// Arrange
+	settings := MaestroSettings{MaxDeliveries: 5, RetryBackoffBaseInSeconds: 15, RetryBackoffMaxInSeconds: 60}
+
+	// Act
+	backOff := consumerBackOff(settings)
+
+	// Assert
+	assert.Equal(t, []time.Duration{15 * time.Second, 30 * time.Second, 60 * time.Second, 60 * time.Second}, backOff)
+	assert.Less(t, len(backOff), settings.MaxDeliveries, "the server rejects a BackOff as long as MaxDeliver")
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name          string
+		deliveries    uint64
+		failures      int
+		wantPublished int
+		wantTermed    bool
+	}{
+		{
+			name:       "left to the consumer backoff before the last delivery",
+			deliveries: 2,
+		},
+		{
+			name:          "dead-lettered on the last delivery",
+			deliveries:    3,
+			wantPublished: 1,
+			wantTermed:    true,
+		},
+		{
+			name:          "dead letter publish retried on the last delivery",
+			deliveries:    3,
+			failures:      2,
+			wantPublished: 3,
+			wantTermed:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			deadLetterCounter, err := noop.NewMeterProvider().Meter("maestro").Int64Counter("maestro.dead-letter.count")
+			require.NoError(t, err)
+
+			js := &fakeJetStream{publishErr: errors.New("nats: timeout"), failures: tt.failures}
+			m := &maestroHandlerV1{
+				settings: MaestroSettings{
+					MaxDeliveries:             3,
+					RetryBackoffBaseInSeconds: 5,
+					RetryBackoffMaxInSeconds:  60,
+				},
+				subject:              "orders",
+				jsClient:             js,
+				statusKV:             &fakeKeyValue{},
+				deadLetterCounter:    deadLetterCounter,
+				deadLetterRetryDelay: time.Millisecond,
+			}
+			msg := &fakeMsg{deliveries: tt.deliveries}
+
+			// Act
+			m.retry(context.Background(), msg, &Order{OrderID: "order-1"}, errors.New("panettiere is sleeping"))
+
+			// Assert
+			assert.Len(t, js.published, tt.wantPublished)
+			assert.Equal(t, tt.wantTermed, msg.termed)
+			assert.False(t, msg.naked, "naks skip the consumer backoff")
+		})
+	}
+}
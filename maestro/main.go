@@ -12,6 +12,7 @@ import (
 	"syscall"
 	"time"
 
+	fornaiov1pb "github.com/taldoflemis/box-box/fornaio/v1"
 	maestrov1pb "github.com/taldoflemis/box-box/maestro/v1"
 	"github.com/taldoflemis/box-box/pacchetto"
 	"github.com/taldoflemis/box-box/pacchetto/telemetry"
@@ -86,10 +87,22 @@ func main() {
 
 	panettiereClient := panettierev1pb.NewPanettiereServiceClient(panettiereConn)
 
+	slog.InfoContext(ctx, "Creating gRPC client to fornaio service")
+	fornaioConn, err := pacchetto.CreateGRPCClient(ctx, settings.Maestro.FornaioClient)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to create fornaio gRPC client", slog.Any("err", err))
+		retcode = 1
+		return
+	}
+	defer fornaioConn.Close()
+
+	fornaioClient := fornaiov1pb.NewFornaioServiceClient(fornaioConn)
+
 	streamName := "ORDERS"
 	subject := "orders"
+	statusBucket := "ORDERS_STATUS"
 	healthcheck := health.NewServer()
-	maestroHandler, err := newMaestroHandlerV1(settings.Maestro, panettiereClient, nc, streamName, subject, healthcheck)
+	maestroHandler, err := newMaestroHandlerV1(settings.Maestro, panettiereClient, fornaioClient, nc, streamName, subject, statusBucket, healthcheck)
 	if err != nil {
 		slog.ErrorContext(ctx, "failed to create maestro handler", slog.Any("err", err))
 		retcode = 1
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	v1Pb "github.com/taldoflemis/box-box/maestro/v1"
+)
+
+// schedule is the state of the maestro shared by the turn loop, the lunch
+// ticker and the gRPC API.
+type schedule struct {
+	lunchPeriod time.Duration
+
+	mu            sync.Mutex
+	state         v1Pb.State
+	since         time.Time
+	paused        bool
+	lunching      bool
+	lunchDue      bool
+	skipNextLunch bool
+	nextLunch     time.Time
+	// resumed is closed by resume, waking up a turn loop waiting while paused
+	resumed chan struct{}
+}
+
+type scheduleSnapshot struct {
+	state         v1Pb.State
+	since         time.Time
+	nextLunch     time.Time
+	skipNextLunch bool
+}
+
+func newSchedule(lunchPeriod time.Duration) *schedule {
+	return &schedule{
+		lunchPeriod: lunchPeriod,
+		state:       v1Pb.State_Working,
+		since:       time.Now(),
+	}
+}
+
+func (s *schedule) snapshot() scheduleSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return scheduleSnapshot{
+		state:         s.state,
+		since:         s.since,
+		nextLunch:     s.nextLunch,
+		skipNextLunch: s.skipNextLunch,
+	}
+}
+
+// updateState must be called with mu held after paused or lunching changed.
+func (s *schedule) updateState() {
+	state := v1Pb.State_Working
+	switch {
+	case s.lunching:
+		state = v1Pb.State_Lunching
+	case s.paused:
+		state = v1Pb.State_Paused
+	}
+
+	if state != s.state {
+		s.state = state
+		s.since = time.Now()
+	}
+}
+
+// pause stops the turn loop from fetching orders and reports whether it was
+// working before.
+func (s *schedule) pause() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.paused {
+		return false
+	}
+
+	s.paused = true
+	s.resumed = make(chan struct{})
+	s.updateState()
+
+	return true
+}
+
+// resume lets the turn loop fetch orders again and reports whether it was paused.
+func (s *schedule) resume() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.paused {
+		return false
+	}
+
+	s.paused = false
+	close(s.resumed)
+	s.updateState()
+
+	return true
+}
+
+// waitWhilePaused blocks until the maestro is resumed or ctx is done.
+func (s *schedule) waitWhilePaused(ctx context.Context) {
+	s.mu.Lock()
+	if !s.paused {
+		s.mu.Unlock()
+		return
+	}
+	resumed := s.resumed
+	s.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+	case <-resumed:
+	}
+}
+
+// planLunch sets the next lunch break one lunch period from now, when the
+// lunch ticker is started or reset.
+func (s *schedule) planLunch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextLunch = time.Now().Add(s.lunchPeriod)
+}
+
+// lunchTime is called by the lunch ticker. The lunch is taken at the end of
+// the current batch unless it was skipped, in which case the following one is
+// planned right away. It reports whether the lunch was skipped.
+func (s *schedule) lunchTime() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.skipNextLunch {
+		s.skipNextLunch = false
+		s.nextLunch = time.Now().Add(s.lunchPeriod)
+		return true
+	}
+
+	s.lunchDue = true
+	return false
+}
+
+// skipLunch makes the maestro work through the next lunch break. A lunch that
+// is already due but not started yet is the one skipped.
+func (s *schedule) skipLunch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.lunchDue {
+		s.lunchDue = false
+		s.nextLunch = s.nextLunch.Add(s.lunchPeriod)
+		return
+	}
+
+	s.skipNextLunch = true
+}
+
+// takeDueLunch reports whether lunch is due, the maestro is expected to go
+// for it once the orders in hand are done.
+func (s *schedule) takeDueLunch() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	due := s.lunchDue
+	s.lunchDue = false
+
+	return due
+}
+
+func (s *schedule) startLunch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lunching = true
+	s.updateState()
+}
+
+func (s *schedule) endLunch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lunching = false
+	s.nextLunch = time.Now().Add(s.lunchPeriod)
+	s.updateState()
+}
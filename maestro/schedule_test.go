@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1Pb "github.com/taldoflemis/box-box/maestro/v1"
+)
+
+func TestSchedulePauseAndResume(t *testing.T) {
+	// Arrange
+	s := newSchedule(time.Minute)
+	waiting := make(chan struct{})
+
+	// Act
+	assert.True(t, s.pause())
+	assert.False(t, s.pause())
+	paused := s.snapshot().state
+
+	go func() {
+		s.waitWhilePaused(context.Background())
+		close(waiting)
+	}()
+	assert.True(t, s.resume())
+
+	// Assert
+	assert.Equal(t, v1Pb.State_Paused, paused)
+	assert.Equal(t, v1Pb.State_Working, s.snapshot().state)
+	assert.Eventually(t, func() bool {
+		select {
+		case <-waiting:
+			return true
+		default:
+			return false
+		}
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestScheduleLunch(t *testing.T) {
+	tests := []struct {
+		name      string
+		act       func(s *schedule)
+		wantLunch bool
+	}{
+		{
+			name:      "lunch is due at lunch time",
+			act:       func(s *schedule) { s.lunchTime() },
+			wantLunch: true,
+		},
+		{
+			name: "next lunch skipped",
+			act: func(s *schedule) {
+				s.skipLunch()
+				s.lunchTime()
+			},
+		},
+		{
+			name: "due lunch skipped",
+			act: func(s *schedule) {
+				s.lunchTime()
+				s.skipLunch()
+			},
+		},
+		{
+			name: "only one lunch skipped",
+			act: func(s *schedule) {
+				s.skipLunch()
+				s.lunchTime()
+				s.lunchTime()
+			},
+			wantLunch: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			s := newSchedule(time.Minute)
+			s.planLunch()
+
+			// Act
+			tt.act(s)
+
+			// Assert
+			assert.Equal(t, tt.wantLunch, s.takeDueLunch())
+			assert.False(t, s.takeDueLunch())
+			assert.False(t, s.snapshot().skipNextLunch)
+		})
+	}
+}
@@ -10,14 +10,27 @@ import (
 var baseConfig []byte
 
 type MaestroSettings struct {
-	PanettiereClient            pacchetto.GRPCClientSettings `mapstructure:"panettiere-client" validate:"required"`
-	SmokingDurationInSeconds    int                          `mapstructure:"smoking-duration-in-seconds" validate:"required,min=1"`
-	ProbabilityOfOversmoking    float64                      `mapstructure:"probability-of-oversmoking" validate:"required,gte=0,lte=1"`
-	OversmokingFactor           float64                      `mapstructure:"oversmoking-factor" validate:"required,gt=1"`
-	PeriodBetweenLunchInSeconds int                          `mapstructure:"period-between-lunch-in-seconds" validate:"required,min=30"`
-	LunchDurationInSeconds      int                          `mapstructure:"lunch-duration-in-seconds" validate:"required,min=1"`
-	OrderBatchSize              int                          `mapstructure:"order-batch-size" validate:"required,min=1"`
-	FetchMaxWaitInSeconds       int                          `mapstructure:"fetch-max-wait-in-seconds" validate:"required,min=5"`
+	PanettiereClient pacchetto.GRPCClientSettings `mapstructure:"panettiere-client" validate:"required"`
+	FornaioClient    pacchetto.GRPCClientSettings `mapstructure:"fornaio-client" validate:"required"`
+	MaxBakeAttempts  int                          `mapstructure:"max-bake-attempts" validate:"required,min=1"`
+	// MaxDeliveries is how often an order is tried before it moves to the
+	// dead-letter subject, the MaxDeliver of the consumer
+	MaxDeliveries int `mapstructure:"max-deliveries" validate:"required,min=1"`
+	// RetryBackoffBaseInSeconds and RetryBackoffMaxInSeconds make the BackOff of
+	// the consumer, its first value is also the ack wait
+	RetryBackoffBaseInSeconds   int     `mapstructure:"retry-backoff-base-in-seconds" validate:"required,min=1"`
+	RetryBackoffMaxInSeconds    int     `mapstructure:"retry-backoff-max-in-seconds" validate:"required,gtefield=RetryBackoffBaseInSeconds"`
+	SmokingDurationInSeconds    int     `mapstructure:"smoking-duration-in-seconds" validate:"required,min=1"`
+	ProbabilityOfOversmoking    float64 `mapstructure:"probability-of-oversmoking" validate:"required,gte=0,lte=1"`
+	OversmokingFactor           float64 `mapstructure:"oversmoking-factor" validate:"required,gt=1"`
+	PeriodBetweenLunchInSeconds int     `mapstructure:"period-between-lunch-in-seconds" validate:"required,min=30"`
+	LunchDurationInSeconds      int     `mapstructure:"lunch-duration-in-seconds" validate:"required,min=1"`
+	OrderBatchSize              int     `mapstructure:"order-batch-size" validate:"required,min=1"`
+	FetchMaxWaitInSeconds       int     `mapstructure:"fetch-max-wait-in-seconds" validate:"required,min=5"`
+	// Workers is how many orders are cooked at the same time
+	Workers int `mapstructure:"workers" validate:"required,min=1"`
+	// InProgressIntervalInSeconds must stay below the ack wait, the shortest BackOff
+	InProgressIntervalInSeconds int `mapstructure:"in-progress-interval-in-seconds" validate:"required,min=1,ltfield=RetryBackoffBaseInSeconds"`
 }
 
 type Settings struct {
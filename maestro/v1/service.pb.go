@@ -9,6 +9,9 @@ package v1
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -21,28 +24,128 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
-// The request message containing the user's name.
-type HelloRequest struct {
+type State int32
+
+const (
+	State_Working  State = 0
+	State_Paused   State = 1
+	State_Lunching State = 2
+)
+
+// Enum value maps for State.
+var (
+	State_name = map[int32]string{
+		0: "Working",
+		1: "Paused",
+		2: "Lunching",
+	}
+	State_value = map[string]int32{
+		"Working":  0,
+		"Paused":   1,
+		"Lunching": 2,
+	}
+)
+
+func (x State) Enum() *State {
+	p := new(State)
+	*p = x
+	return p
+}
+
+func (x State) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (State) Descriptor() protoreflect.EnumDescriptor {
+	return file_maestro_v1_service_proto_enumTypes[0].Descriptor()
+}
+
+func (State) Type() protoreflect.EnumType {
+	return &file_maestro_v1_service_proto_enumTypes[0]
+}
+
+func (x State) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use State.Descriptor instead.
+func (State) EnumDescriptor() ([]byte, []int) {
+	return file_maestro_v1_service_proto_rawDescGZIP(), []int{0}
+}
+
+type WorkerState int32
+
+const (
+	WorkerState_WorkerIdle    WorkerState = 0
+	WorkerState_WorkerCooking WorkerState = 1
+	WorkerState_WorkerSmoking WorkerState = 2
+)
+
+// Enum value maps for WorkerState.
+var (
+	WorkerState_name = map[int32]string{
+		0: "WorkerIdle",
+		1: "WorkerCooking",
+		2: "WorkerSmoking",
+	}
+	WorkerState_value = map[string]int32{
+		"WorkerIdle":    0,
+		"WorkerCooking": 1,
+		"WorkerSmoking": 2,
+	}
+)
+
+func (x WorkerState) Enum() *WorkerState {
+	p := new(WorkerState)
+	*p = x
+	return p
+}
+
+func (x WorkerState) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (WorkerState) Descriptor() protoreflect.EnumDescriptor {
+	return file_maestro_v1_service_proto_enumTypes[1].Descriptor()
+}
+
+func (WorkerState) Type() protoreflect.EnumType {
+	return &file_maestro_v1_service_proto_enumTypes[1]
+}
+
+func (x WorkerState) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use WorkerState.Descriptor instead.
+func (WorkerState) EnumDescriptor() ([]byte, []int) {
+	return file_maestro_v1_service_proto_rawDescGZIP(), []int{1}
+}
+
+type WorkerStatus struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	State         WorkerState            `protobuf:"varint,2,opt,name=state,proto3,enum=maestro.v1.WorkerState" json:"state,omitempty"`
+	OrderId       string                 `protobuf:"bytes,3,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	Since         *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=since,proto3" json:"since,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *HelloRequest) Reset() {
-	*x = HelloRequest{}
+func (x *WorkerStatus) Reset() {
+	*x = WorkerStatus{}
 	mi := &file_maestro_v1_service_proto_msgTypes[0]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *HelloRequest) String() string {
+func (x *WorkerStatus) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*HelloRequest) ProtoMessage() {}
+func (*WorkerStatus) ProtoMessage() {}
 
-func (x *HelloRequest) ProtoReflect() protoreflect.Message {
+func (x *WorkerStatus) ProtoReflect() protoreflect.Message {
 	mi := &file_maestro_v1_service_proto_msgTypes[0]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -54,40 +157,66 @@ func (x *HelloRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use HelloRequest.ProtoReflect.Descriptor instead.
-func (*HelloRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use WorkerStatus.ProtoReflect.Descriptor instead.
+func (*WorkerStatus) Descriptor() ([]byte, []int) {
 	return file_maestro_v1_service_proto_rawDescGZIP(), []int{0}
 }
 
-func (x *HelloRequest) GetName() string {
+func (x *WorkerStatus) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *WorkerStatus) GetState() WorkerState {
+	if x != nil {
+		return x.State
+	}
+	return WorkerState_WorkerIdle
+}
+
+func (x *WorkerStatus) GetOrderId() string {
 	if x != nil {
-		return x.Name
+		return x.OrderId
 	}
 	return ""
 }
 
-// The response message containing the greetings
-type HelloReply struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Message       string                 `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *WorkerStatus) GetSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Since
+	}
+	return nil
 }
 
-func (x *HelloReply) Reset() {
-	*x = HelloReply{}
+type StatusResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	State          State                  `protobuf:"varint,1,opt,name=state,proto3,enum=maestro.v1.State" json:"state,omitempty"`
+	Since          *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=since,proto3" json:"since,omitempty"`
+	TimeInState    *durationpb.Duration   `protobuf:"bytes,3,opt,name=time_in_state,json=timeInState,proto3" json:"time_in_state,omitempty"`
+	NextLunch      *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=next_lunch,json=nextLunch,proto3" json:"next_lunch,omitempty"`
+	SkipNextLunch  bool                   `protobuf:"varint,5,opt,name=skip_next_lunch,json=skipNextLunch,proto3" json:"skip_next_lunch,omitempty"`
+	Workers        []*WorkerStatus        `protobuf:"bytes,6,rep,name=workers,proto3" json:"workers,omitempty"`
+	InFlightOrders int32                  `protobuf:"varint,7,opt,name=in_flight_orders,json=inFlightOrders,proto3" json:"in_flight_orders,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *StatusResponse) Reset() {
+	*x = StatusResponse{}
 	mi := &file_maestro_v1_service_proto_msgTypes[1]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *HelloReply) String() string {
+func (x *StatusResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*HelloReply) ProtoMessage() {}
+func (*StatusResponse) ProtoMessage() {}
 
-func (x *HelloReply) ProtoReflect() protoreflect.Message {
+func (x *StatusResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_maestro_v1_service_proto_msgTypes[1]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -99,31 +228,226 @@ func (x *HelloReply) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use HelloReply.ProtoReflect.Descriptor instead.
-func (*HelloReply) Descriptor() ([]byte, []int) {
+// Deprecated: Use StatusResponse.ProtoReflect.Descriptor instead.
+func (*StatusResponse) Descriptor() ([]byte, []int) {
 	return file_maestro_v1_service_proto_rawDescGZIP(), []int{1}
 }
 
-func (x *HelloReply) GetMessage() string {
+func (x *StatusResponse) GetState() State {
+	if x != nil {
+		return x.State
+	}
+	return State_Working
+}
+
+func (x *StatusResponse) GetSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Since
+	}
+	return nil
+}
+
+func (x *StatusResponse) GetTimeInState() *durationpb.Duration {
+	if x != nil {
+		return x.TimeInState
+	}
+	return nil
+}
+
+func (x *StatusResponse) GetNextLunch() *timestamppb.Timestamp {
+	if x != nil {
+		return x.NextLunch
+	}
+	return nil
+}
+
+func (x *StatusResponse) GetSkipNextLunch() bool {
+	if x != nil {
+		return x.SkipNextLunch
+	}
+	return false
+}
+
+func (x *StatusResponse) GetWorkers() []*WorkerStatus {
 	if x != nil {
-		return x.Message
+		return x.Workers
+	}
+	return nil
+}
+
+func (x *StatusResponse) GetInFlightOrders() int32 {
+	if x != nil {
+		return x.InFlightOrders
+	}
+	return 0
+}
+
+type InFlightOrder struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	OrderId        string                 `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	StreamSequence uint64                 `protobuf:"varint,2,opt,name=stream_sequence,json=streamSequence,proto3" json:"stream_sequence,omitempty"`
+	Delivery       uint64                 `protobuf:"varint,3,opt,name=delivery,proto3" json:"delivery,omitempty"`
+	FetchedAt      *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=fetched_at,json=fetchedAt,proto3" json:"fetched_at,omitempty"`
+	WorkerId       int32                  `protobuf:"varint,5,opt,name=worker_id,json=workerId,proto3" json:"worker_id,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *InFlightOrder) Reset() {
+	*x = InFlightOrder{}
+	mi := &file_maestro_v1_service_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InFlightOrder) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InFlightOrder) ProtoMessage() {}
+
+func (x *InFlightOrder) ProtoReflect() protoreflect.Message {
+	mi := &file_maestro_v1_service_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InFlightOrder.ProtoReflect.Descriptor instead.
+func (*InFlightOrder) Descriptor() ([]byte, []int) {
+	return file_maestro_v1_service_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *InFlightOrder) GetOrderId() string {
+	if x != nil {
+		return x.OrderId
 	}
 	return ""
 }
 
+func (x *InFlightOrder) GetStreamSequence() uint64 {
+	if x != nil {
+		return x.StreamSequence
+	}
+	return 0
+}
+
+func (x *InFlightOrder) GetDelivery() uint64 {
+	if x != nil {
+		return x.Delivery
+	}
+	return 0
+}
+
+func (x *InFlightOrder) GetFetchedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.FetchedAt
+	}
+	return nil
+}
+
+func (x *InFlightOrder) GetWorkerId() int32 {
+	if x != nil {
+		return x.WorkerId
+	}
+	return 0
+}
+
+type ListInFlightOrdersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Orders        []*InFlightOrder       `protobuf:"bytes,1,rep,name=orders,proto3" json:"orders,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListInFlightOrdersResponse) Reset() {
+	*x = ListInFlightOrdersResponse{}
+	mi := &file_maestro_v1_service_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListInFlightOrdersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListInFlightOrdersResponse) ProtoMessage() {}
+
+func (x *ListInFlightOrdersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_maestro_v1_service_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListInFlightOrdersResponse.ProtoReflect.Descriptor instead.
+func (*ListInFlightOrdersResponse) Descriptor() ([]byte, []int) {
+	return file_maestro_v1_service_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListInFlightOrdersResponse) GetOrders() []*InFlightOrder {
+	if x != nil {
+		return x.Orders
+	}
+	return nil
+}
+
 var File_maestro_v1_service_proto protoreflect.FileDescriptor
 
 const file_maestro_v1_service_proto_rawDesc = "" +
 	"\n" +
 	"\x18maestro/v1/service.proto\x12\n" +
-	"maestro.v1\"\"\n" +
-	"\fHelloRequest\x12\x12\n" +
-	"\x04name\x18\x01 \x01(\tR\x04name\"&\n" +
+	"maestro.v1\x1a\x1egoogle/protobuf/duration.proto\x1a\x1bgoogle/protobuf/empty.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\x9a\x01\n" +
+	"\fWorkerStatus\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12-\n" +
+	"\x05state\x18\x02 \x01(\x0e2\x17.maestro.v1.WorkerStateR\x05state\x12\x19\n" +
+	"\border_id\x18\x03 \x01(\tR\aorderId\x120\n" +
+	"\x05since\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x05since\"\xeb\x02\n" +
+	"\x0eStatusResponse\x12'\n" +
+	"\x05state\x18\x01 \x01(\x0e2\x11.maestro.v1.StateR\x05state\x120\n" +
+	"\x05since\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x05since\x12=\n" +
+	"\rtime_in_state\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\vtimeInState\x129\n" +
+	"\n" +
+	"next_lunch\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tnextLunch\x12&\n" +
+	"\x0fskip_next_lunch\x18\x05 \x01(\bR\rskipNextLunch\x122\n" +
+	"\aworkers\x18\x06 \x03(\v2\x18.maestro.v1.WorkerStatusR\aworkers\x12(\n" +
+	"\x10in_flight_orders\x18\a \x01(\x05R\x0einFlightOrders\"\xc7\x01\n" +
+	"\rInFlightOrder\x12\x19\n" +
+	"\border_id\x18\x01 \x01(\tR\aorderId\x12'\n" +
+	"\x0fstream_sequence\x18\x02 \x01(\x04R\x0estreamSequence\x12\x1a\n" +
+	"\bdelivery\x18\x03 \x01(\x04R\bdelivery\x129\n" +
+	"\n" +
+	"fetched_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tfetchedAt\x12\x1b\n" +
+	"\tworker_id\x18\x05 \x01(\x05R\bworkerId\"O\n" +
+	"\x1aListInFlightOrdersResponse\x121\n" +
+	"\x06orders\x18\x01 \x03(\v2\x19.maestro.v1.InFlightOrderR\x06orders*.\n" +
+	"\x05State\x12\v\n" +
+	"\aWorking\x10\x00\x12\n" +
+	"\n" +
+	"\x06Paused\x10\x01\x12\f\n" +
+	"\bLunching\x10\x02*C\n" +
+	"\vWorkerState\x12\x0e\n" +
 	"\n" +
-	"HelloReply\x12\x18\n" +
-	"\amessage\x18\x01 \x01(\tR\amessage2P\n" +
-	"\x0eMaestroService\x12>\n" +
-	"\bSayHello\x12\x18.maestro.v1.HelloRequest\x1a\x16.maestro.v1.HelloReply\"\x00B+Z)github.com/taldoflemis/box-box/maestro/v1b\x06proto3"
+	"WorkerIdle\x10\x00\x12\x11\n" +
+	"\rWorkerCooking\x10\x01\x12\x11\n" +
+	"\rWorkerSmoking\x10\x022\xb8\x03\n" +
+	"\x0eMaestroService\x12A\n" +
+	"\tGetStatus\x12\x16.google.protobuf.Empty\x1a\x1a.maestro.v1.StatusResponse\"\x00\x12=\n" +
+	"\x05Pause\x12\x16.google.protobuf.Empty\x1a\x1a.maestro.v1.StatusResponse\"\x00\x12>\n" +
+	"\x06Resume\x12\x16.google.protobuf.Empty\x1a\x1a.maestro.v1.StatusResponse\"\x00\x12E\n" +
+	"\rSkipNextLunch\x12\x16.google.protobuf.Empty\x1a\x1a.maestro.v1.StatusResponse\"\x00\x12V\n" +
+	"\x12ListInFlightOrders\x12\x16.google.protobuf.Empty\x1a&.maestro.v1.ListInFlightOrdersResponse\"\x00\x12E\n" +
+	"\vWatchStatus\x12\x16.google.protobuf.Empty\x1a\x1a.maestro.v1.StatusResponse\"\x000\x01B+Z)github.com/taldoflemis/box-box/maestro/v1b\x06proto3"
 
 var (
 	file_maestro_v1_service_proto_rawDescOnce sync.Once
@@ -137,19 +461,46 @@ func file_maestro_v1_service_proto_rawDescGZIP() []byte {
 	return file_maestro_v1_service_proto_rawDescData
 }
 
-var file_maestro_v1_service_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_maestro_v1_service_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_maestro_v1_service_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
 var file_maestro_v1_service_proto_goTypes = []any{
-	(*HelloRequest)(nil), // 0: maestro.v1.HelloRequest
-	(*HelloReply)(nil),   // 1: maestro.v1.HelloReply
+	(State)(0),                         // 0: maestro.v1.State
+	(WorkerState)(0),                   // 1: maestro.v1.WorkerState
+	(*WorkerStatus)(nil),               // 2: maestro.v1.WorkerStatus
+	(*StatusResponse)(nil),             // 3: maestro.v1.StatusResponse
+	(*InFlightOrder)(nil),              // 4: maestro.v1.InFlightOrder
+	(*ListInFlightOrdersResponse)(nil), // 5: maestro.v1.ListInFlightOrdersResponse
+	(*timestamppb.Timestamp)(nil),      // 6: google.protobuf.Timestamp
+	(*durationpb.Duration)(nil),        // 7: google.protobuf.Duration
+	(*emptypb.Empty)(nil),              // 8: google.protobuf.Empty
 }
 var file_maestro_v1_service_proto_depIdxs = []int32{
-	0, // 0: maestro.v1.MaestroService.SayHello:input_type -> maestro.v1.HelloRequest
-	1, // 1: maestro.v1.MaestroService.SayHello:output_type -> maestro.v1.HelloReply
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	1,  // 0: maestro.v1.WorkerStatus.state:type_name -> maestro.v1.WorkerState
+	6,  // 1: maestro.v1.WorkerStatus.since:type_name -> google.protobuf.Timestamp
+	0,  // 2: maestro.v1.StatusResponse.state:type_name -> maestro.v1.State
+	6,  // 3: maestro.v1.StatusResponse.since:type_name -> google.protobuf.Timestamp
+	7,  // 4: maestro.v1.StatusResponse.time_in_state:type_name -> google.protobuf.Duration
+	6,  // 5: maestro.v1.StatusResponse.next_lunch:type_name -> google.protobuf.Timestamp
+	2,  // 6: maestro.v1.StatusResponse.workers:type_name -> maestro.v1.WorkerStatus
+	6,  // 7: maestro.v1.InFlightOrder.fetched_at:type_name -> google.protobuf.Timestamp
+	4,  // 8: maestro.v1.ListInFlightOrdersResponse.orders:type_name -> maestro.v1.InFlightOrder
+	8,  // 9: maestro.v1.MaestroService.GetStatus:input_type -> google.protobuf.Empty
+	8,  // 10: maestro.v1.MaestroService.Pause:input_type -> google.protobuf.Empty
+	8,  // 11: maestro.v1.MaestroService.Resume:input_type -> google.protobuf.Empty
+	8,  // 12: maestro.v1.MaestroService.SkipNextLunch:input_type -> google.protobuf.Empty
+	8,  // 13: maestro.v1.MaestroService.ListInFlightOrders:input_type -> google.protobuf.Empty
+	8,  // 14: maestro.v1.MaestroService.WatchStatus:input_type -> google.protobuf.Empty
+	3,  // 15: maestro.v1.MaestroService.GetStatus:output_type -> maestro.v1.StatusResponse
+	3,  // 16: maestro.v1.MaestroService.Pause:output_type -> maestro.v1.StatusResponse
+	3,  // 17: maestro.v1.MaestroService.Resume:output_type -> maestro.v1.StatusResponse
+	3,  // 18: maestro.v1.MaestroService.SkipNextLunch:output_type -> maestro.v1.StatusResponse
+	5,  // 19: maestro.v1.MaestroService.ListInFlightOrders:output_type -> maestro.v1.ListInFlightOrdersResponse
+	3,  // 20: maestro.v1.MaestroService.WatchStatus:output_type -> maestro.v1.StatusResponse
+	15, // [15:21] is the sub-list for method output_type
+	9,  // [9:15] is the sub-list for method input_type
+	9,  // [9:9] is the sub-list for extension type_name
+	9,  // [9:9] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
 }
 
 func init() { file_maestro_v1_service_proto_init() }
@@ -162,13 +513,14 @@ func file_maestro_v1_service_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_maestro_v1_service_proto_rawDesc), len(file_maestro_v1_service_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   2,
+			NumEnums:      2,
+			NumMessages:   4,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_maestro_v1_service_proto_goTypes,
 		DependencyIndexes: file_maestro_v1_service_proto_depIdxs,
+		EnumInfos:         file_maestro_v1_service_proto_enumTypes,
 		MessageInfos:      file_maestro_v1_service_proto_msgTypes,
 	}.Build()
 	File_maestro_v1_service_proto = out.File
@@ -11,6 +11,7 @@ import (
 	grpc "google.golang.org/grpc"
 	codes "google.golang.org/grpc/codes"
 	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
 )
 
 // This is a compile-time assertion to ensure that this generated file
@@ -19,14 +20,24 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	MaestroService_SayHello_FullMethodName = "/maestro.v1.MaestroService/SayHello"
+	MaestroService_GetStatus_FullMethodName          = "/maestro.v1.MaestroService/GetStatus"
+	MaestroService_Pause_FullMethodName              = "/maestro.v1.MaestroService/Pause"
+	MaestroService_Resume_FullMethodName             = "/maestro.v1.MaestroService/Resume"
+	MaestroService_SkipNextLunch_FullMethodName      = "/maestro.v1.MaestroService/SkipNextLunch"
+	MaestroService_ListInFlightOrders_FullMethodName = "/maestro.v1.MaestroService/ListInFlightOrders"
+	MaestroService_WatchStatus_FullMethodName        = "/maestro.v1.MaestroService/WatchStatus"
 )
 
 // MaestroServiceClient is the client API for MaestroService service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type MaestroServiceClient interface {
-	SayHello(ctx context.Context, in *HelloRequest, opts ...grpc.CallOption) (*HelloReply, error)
+	GetStatus(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*StatusResponse, error)
+	Pause(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*StatusResponse, error)
+	Resume(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*StatusResponse, error)
+	SkipNextLunch(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*StatusResponse, error)
+	ListInFlightOrders(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListInFlightOrdersResponse, error)
+	WatchStatus(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StatusResponse], error)
 }
 
 type maestroServiceClient struct {
@@ -37,21 +48,85 @@ func NewMaestroServiceClient(cc grpc.ClientConnInterface) MaestroServiceClient {
 	return &maestroServiceClient{cc}
 }
 
-func (c *maestroServiceClient) SayHello(ctx context.Context, in *HelloRequest, opts ...grpc.CallOption) (*HelloReply, error) {
+func (c *maestroServiceClient) GetStatus(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*StatusResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	out := new(HelloReply)
-	err := c.cc.Invoke(ctx, MaestroService_SayHello_FullMethodName, in, out, cOpts...)
+	out := new(StatusResponse)
+	err := c.cc.Invoke(ctx, MaestroService_GetStatus_FullMethodName, in, out, cOpts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
+func (c *maestroServiceClient) Pause(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*StatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StatusResponse)
+	err := c.cc.Invoke(ctx, MaestroService_Pause_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *maestroServiceClient) Resume(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*StatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StatusResponse)
+	err := c.cc.Invoke(ctx, MaestroService_Resume_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *maestroServiceClient) SkipNextLunch(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*StatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StatusResponse)
+	err := c.cc.Invoke(ctx, MaestroService_SkipNextLunch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *maestroServiceClient) ListInFlightOrders(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListInFlightOrdersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListInFlightOrdersResponse)
+	err := c.cc.Invoke(ctx, MaestroService_ListInFlightOrders_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *maestroServiceClient) WatchStatus(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StatusResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MaestroService_ServiceDesc.Streams[0], MaestroService_WatchStatus_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[emptypb.Empty, StatusResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MaestroService_WatchStatusClient = grpc.ServerStreamingClient[StatusResponse]
+
 // MaestroServiceServer is the server API for MaestroService service.
 // All implementations must embed UnimplementedMaestroServiceServer
 // for forward compatibility.
 type MaestroServiceServer interface {
-	SayHello(context.Context, *HelloRequest) (*HelloReply, error)
+	GetStatus(context.Context, *emptypb.Empty) (*StatusResponse, error)
+	Pause(context.Context, *emptypb.Empty) (*StatusResponse, error)
+	Resume(context.Context, *emptypb.Empty) (*StatusResponse, error)
+	SkipNextLunch(context.Context, *emptypb.Empty) (*StatusResponse, error)
+	ListInFlightOrders(context.Context, *emptypb.Empty) (*ListInFlightOrdersResponse, error)
+	WatchStatus(*emptypb.Empty, grpc.ServerStreamingServer[StatusResponse]) error
 	mustEmbedUnimplementedMaestroServiceServer()
 }
 
@@ -62,8 +137,23 @@ type MaestroServiceServer interface {
 // pointer dereference when methods are called.
 type UnimplementedMaestroServiceServer struct{}
 
-func (UnimplementedMaestroServiceServer) SayHello(context.Context, *HelloRequest) (*HelloReply, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method SayHello not implemented")
+func (UnimplementedMaestroServiceServer) GetStatus(context.Context, *emptypb.Empty) (*StatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStatus not implemented")
+}
+func (UnimplementedMaestroServiceServer) Pause(context.Context, *emptypb.Empty) (*StatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Pause not implemented")
+}
+func (UnimplementedMaestroServiceServer) Resume(context.Context, *emptypb.Empty) (*StatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Resume not implemented")
+}
+func (UnimplementedMaestroServiceServer) SkipNextLunch(context.Context, *emptypb.Empty) (*StatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SkipNextLunch not implemented")
+}
+func (UnimplementedMaestroServiceServer) ListInFlightOrders(context.Context, *emptypb.Empty) (*ListInFlightOrdersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListInFlightOrders not implemented")
+}
+func (UnimplementedMaestroServiceServer) WatchStatus(*emptypb.Empty, grpc.ServerStreamingServer[StatusResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchStatus not implemented")
 }
 func (UnimplementedMaestroServiceServer) mustEmbedUnimplementedMaestroServiceServer() {}
 func (UnimplementedMaestroServiceServer) testEmbeddedByValue()                        {}
@@ -86,24 +176,107 @@ func RegisterMaestroServiceServer(s grpc.ServiceRegistrar, srv MaestroServiceSer
 	s.RegisterService(&MaestroService_ServiceDesc, srv)
 }
 
-func _MaestroService_SayHello_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(HelloRequest)
+func _MaestroService_GetStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(MaestroServiceServer).SayHello(ctx, in)
+		return srv.(MaestroServiceServer).GetStatus(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: MaestroService_SayHello_FullMethodName,
+		FullMethod: MaestroService_GetStatus_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MaestroServiceServer).SayHello(ctx, req.(*HelloRequest))
+		return srv.(MaestroServiceServer).GetStatus(ctx, req.(*emptypb.Empty))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MaestroService_Pause_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MaestroServiceServer).Pause(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MaestroService_Pause_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MaestroServiceServer).Pause(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MaestroService_Resume_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MaestroServiceServer).Resume(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MaestroService_Resume_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MaestroServiceServer).Resume(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MaestroService_SkipNextLunch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MaestroServiceServer).SkipNextLunch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MaestroService_SkipNextLunch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MaestroServiceServer).SkipNextLunch(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MaestroService_ListInFlightOrders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MaestroServiceServer).ListInFlightOrders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MaestroService_ListInFlightOrders_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MaestroServiceServer).ListInFlightOrders(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MaestroService_WatchStatus_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(emptypb.Empty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MaestroServiceServer).WatchStatus(m, &grpc.GenericServerStream[emptypb.Empty, StatusResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MaestroService_WatchStatusServer = grpc.ServerStreamingServer[StatusResponse]
+
 // MaestroService_ServiceDesc is the grpc.ServiceDesc for MaestroService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -112,10 +285,32 @@ var MaestroService_ServiceDesc = grpc.ServiceDesc{
 	HandlerType: (*MaestroServiceServer)(nil),
 	Methods: []grpc.MethodDesc{
 		{
-			MethodName: "SayHello",
-			Handler:    _MaestroService_SayHello_Handler,
+			MethodName: "GetStatus",
+			Handler:    _MaestroService_GetStatus_Handler,
+		},
+		{
+			MethodName: "Pause",
+			Handler:    _MaestroService_Pause_Handler,
+		},
+		{
+			MethodName: "Resume",
+			Handler:    _MaestroService_Resume_Handler,
+		},
+		{
+			MethodName: "SkipNextLunch",
+			Handler:    _MaestroService_SkipNextLunch_Handler,
+		},
+		{
+			MethodName: "ListInFlightOrders",
+			Handler:    _MaestroService_ListInFlightOrders_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchStatus",
+			Handler:       _MaestroService_WatchStatus_Handler,
+			ServerStreams: true,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "maestro/v1/service.proto",
 }
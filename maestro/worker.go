@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+type workerState string
+
+const (
+	workerIdle    workerState = "idle"
+	workerCooking workerState = "cooking"
+	workerSmoking workerState = "smoking"
+)
+
+// worker is one of the cooks working under the maestro. Each cooks one order
+// at a time and smokes after it on their own, so a smoking worker does not
+// hold the others back.
+type worker struct {
+	id          int
+	busyWorkers metric.Int64UpDownCounter
+
+	mu      sync.Mutex
+	state   workerState
+	orderID string
+	since   time.Time
+}
+
+func newWorker(id int, busyWorkers metric.Int64UpDownCounter) *worker {
+	return &worker{
+		id:          id,
+		busyWorkers: busyWorkers,
+		state:       workerIdle,
+		since:       time.Now(),
+	}
+}
+
+// setState moves the worker to state, working on orderID, and keeps the
+// maestro.workers.busy counter in line with it.
+func (w *worker) setState(ctx context.Context, state workerState, orderID string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.state != workerIdle {
+		w.busyWorkers.Add(ctx, -1, w.metricAttributes())
+	}
+
+	w.state = state
+	w.orderID = orderID
+	w.since = time.Now()
+
+	if w.state != workerIdle {
+		w.busyWorkers.Add(ctx, 1, w.metricAttributes())
+	}
+}
+
+// snapshot returns the state of the worker, the order it works on and since when.
+func (w *worker) snapshot() (workerState, string, time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.state, w.orderID, w.since
+}
+
+// metricAttributes must be called with mu held.
+func (w *worker) metricAttributes() metric.MeasurementOption {
+	return metric.WithAttributes(
+		attribute.Int("maestro.worker.id", w.id),
+		attribute.String("maestro.worker.state", string(w.state)),
+	)
+}
+
+// inFlightOrder is an order handed to the worker pool. Its message is kept in
+// progress from the moment it is fetched until stop is called.
+type inFlightOrder struct {
+	msg       jetstream.Msg
+	stop      context.CancelFunc
+	orderID   string
+	sequence  uint64
+	delivery  uint64
+	fetchedAt time.Time
+	workerID  int // 0 while the order waits for a free worker
+}
+
+func newInFlightOrder(msg jetstream.Msg, stop context.CancelFunc) *inFlightOrder {
+	subject := msg.Subject()
+	order := &inFlightOrder{
+		msg:       msg,
+		stop:      stop,
+		orderID:   subject[strings.LastIndex(subject, ".")+1:],
+		fetchedAt: time.Now(),
+	}
+
+	if meta, err := msg.Metadata(); err == nil {
+		order.sequence = meta.Sequence.Stream
+		order.delivery = meta.NumDelivered
+	}
+
+	return order
+}
+
+// inFlightOrders are the orders fetched and not finished by their worker yet,
+// smoke included.
+type inFlightOrders struct {
+	mu     sync.Mutex
+	orders map[*inFlightOrder]struct{}
+	wg     sync.WaitGroup
+}
+
+func newInFlightOrders() *inFlightOrders {
+	return &inFlightOrders{orders: make(map[*inFlightOrder]struct{})}
+}
+
+func (o *inFlightOrders) add(order *inFlightOrder) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.orders[order] = struct{}{}
+	o.wg.Add(1)
+}
+
+func (o *inFlightOrders) assign(order *inFlightOrder, w *worker) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	order.workerID = w.id
+}
+
+func (o *inFlightOrders) done(order *inFlightOrder) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	delete(o.orders, order)
+	o.wg.Done()
+}
+
+// wait blocks until every order added so far is done.
+func (o *inFlightOrders) wait() {
+	o.wg.Wait()
+}
+
+// list returns a copy of the orders in flight, oldest first.
+func (o *inFlightOrders) list() []inFlightOrder {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	orders := make([]inFlightOrder, 0, len(o.orders))
+	for order := range o.orders {
+		orders = append(orders, *order)
+	}
+
+	slices.SortFunc(orders, func(a, b inFlightOrder) int {
+		return a.fetchedAt.Compare(b.fetchedAt)
+	})
+
+	return orders
+}
+
+func (o *inFlightOrders) len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return len(o.orders)
+}
+
+// keepInProgress extends the ack deadline of msg every InProgressIntervalInSeconds
+// until ctx is done, so orders waiting for a worker or taking long to cook are
+// not redelivered to another maestro.
+func (m *maestroHandlerV1) keepInProgress(ctx context.Context, msg jetstream.Msg) {
+	ticker := time.NewTicker(time.Duration(m.settings.InProgressIntervalInSeconds) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		err := msg.InProgress()
+		if errors.Is(err, jetstream.ErrMsgAlreadyAckd) {
+			return
+		}
+		if err != nil {
+			slog.WarnContext(ctx, "failed to set message in progress", slog.Any("err", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// keepBatchInProgress starts keeping every message of the batch in progress
+// as soon as it arrives, before a worker is free to take it.
+func (m *maestroHandlerV1) keepBatchInProgress(ctx context.Context, msgs <-chan jetstream.Msg) <-chan *inFlightOrder {
+	// Sized to the batch, so messages are never left waiting without a heartbeat
+	orders := make(chan *inFlightOrder, m.settings.OrderBatchSize)
+
+	go func() {
+		defer close(orders)
+
+		for msg := range msgs {
+			heartbeatCtx, stop := context.WithCancel(ctx)
+			go m.keepInProgress(heartbeatCtx, msg)
+
+			orders <- newInFlightOrder(msg, stop)
+		}
+	}()
+
+	return orders
+}
+
+// work cooks the orders handed to the pool until orders is closed.
+func (m *maestroHandlerV1) work(w *worker, orders <-chan *inFlightOrder) {
+	slog.Debug("Worker is ready", slog.Int("worker-id", w.id))
+
+	for order := range orders {
+		ctx := context.Background()
+		m.inFlight.assign(order, w)
+
+		cooked, ok := m.processNewOrder(ctx, w, order.msg)
+		order.stop()
+
+		if ok {
+			m.smoke(ctx, w, cooked)
+		}
+		w.setState(ctx, workerIdle, "")
+		m.publishStatus()
+
+		m.inFlight.done(order)
+	}
+
+	slog.Debug("Worker went home", slog.Int("worker-id", w.id))
+}
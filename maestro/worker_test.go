@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+func TestWorkerSetState(t *testing.T) {
+	// Arrange
+	busyWorkers, err := noop.NewMeterProvider().Meter("test").Int64UpDownCounter("busy")
+	assert.NoError(t, err)
+	w := newWorker(1, busyWorkers)
+	_, _, idleSince := w.snapshot()
+
+	// Act
+	w.setState(context.Background(), workerCooking, "abc")
+
+	// Assert
+	state, orderID, since := w.snapshot()
+	assert.Equal(t, workerCooking, state)
+	assert.Equal(t, "abc", orderID)
+	assert.False(t, since.Before(idleSince))
+}
@@ -0,0 +1,22 @@
+package pacchetto
+
+// Headers of the messages republished to the dead-letter subject of a stream,
+// describing why and where the original message failed. The body is the
+// original message.
+const (
+	DeadLetterErrorHeader      = "Dead-Letter-Error"
+	DeadLetterReasonHeader     = "Dead-Letter-Reason"
+	DeadLetterSubjectHeader    = "Dead-Letter-Original-Subject"
+	DeadLetterSequenceHeader   = "Dead-Letter-Original-Sequence"
+	DeadLetterConsumerHeader   = "Dead-Letter-Consumer"
+	DeadLetterDeliveriesHeader = "Dead-Letter-Deliveries"
+	DeadLetterFailedAtHeader   = "Dead-Letter-Failed-At"
+)
+
+// Values of DeadLetterReasonHeader.
+const (
+	// DeadLetterReasonMalformed messages cannot be decoded and were never retried
+	DeadLetterReasonMalformed = "malformed"
+	// DeadLetterReasonMaxDeliveries messages failed on every delivery allowed
+	DeadLetterReasonMaxDeliveries = "max_deliveries"
+)
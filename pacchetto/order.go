@@ -0,0 +1,169 @@
+package pacchetto
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// orderStatusCancelled is the status the gateway gives an order the customer
+// cancelled. It is final: later moves of the order never replace it in the
+// status bucket.
+const orderStatusCancelled = "cancelled"
+
+// statusBucketAttempts is how often a status bucket write is tried again after
+// losing a race with another writer.
+const statusBucketAttempts = 3
+
+type OrderItem struct {
+	Size      string   `json:"size"`
+	Border    string   `json:"border"`
+	Toppings  []string `json:"toppings"`
+	Quantity  int      `json:"quantity"`
+	Prepared  int      `json:"prepared"` // Pizzas maestro already baked for this item
+	UnitPrice int64    `json:"unit_price,omitempty"`
+	Total     int64    `json:"total,omitempty"`
+}
+
+// Order is the message every service passes along the ORDERS stream and
+// writes to the order status bucket.
+type Order struct {
+	Items []OrderItem `json:"items"`
+	// Single pizza of orders placed before line items, see NormalizeItems
+	Size        string               `json:"size,omitempty"`
+	Border      string               `json:"border,omitempty"`
+	Toppings    []string             `json:"toppings,omitempty"`
+	Destination string               `json:"destination"`
+	Username    string               `json:"username"`
+	OrderedAt   time.Time            `json:"ordered_at"`
+	OrderID     string               `json:"order_id"`
+	Status      string               `json:"status"`               // e.g., "waiting_payment", "waiting_to_cook", "waiting_delivery", "delivered"
+	Timestamps  map[string]time.Time `json:"timestamps,omitempty"` // When the order entered each status
+	Reason      string               `json:"reason,omitempty"`     // Why the payment failed or the kitchen rejected the order
+	Subtotal    int64                `json:"subtotal,omitempty"`
+	PromoCode   string               `json:"promo_code,omitempty"`
+	Discount    int64                `json:"discount,omitempty"` // Taken off the subtotal by the promo code
+	Total       int64                `json:"total,omitempty"`
+	Currency    string               `json:"currency,omitempty"`   // Prices are in minor units of the currency
+	PaymentID   string               `json:"payment_id,omitempty"` // Set by caixa once the order is paid
+	Rider       string               `json:"rider,omitempty"`      // Set by corriere once the order leaves the pizzeria
+}
+
+// NormalizeItems turns an order placed before line items existed into a single item.
+func (o *Order) NormalizeItems() {
+	if len(o.Items) > 0 || o.Size == "" {
+		return
+	}
+
+	o.Items = []OrderItem{{Size: o.Size, Border: o.Border, Toppings: o.Toppings, Quantity: 1}}
+	o.Size, o.Border, o.Toppings = "", "", nil
+}
+
+// Pizzas is the number of pizzas in the order.
+func (o *Order) Pizzas() int {
+	total := 0
+	for _, item := range o.Items {
+		total += item.Quantity
+	}
+	return total
+}
+
+// CreateOrderStatusBucket creates the key-value bucket that keeps the latest
+// state of every order, or returns it when it exists. Every service creates it
+// so none depends on another one starting first.
+func CreateOrderStatusBucket(ctx context.Context, js jetstream.JetStream, bucket string) (jetstream.KeyValue, error) {
+	return js.CreateOrUpdateKeyValue(ctx, jetstream.KeyValueConfig{
+		Bucket:      bucket,
+		Description: "Latest known state of every order",
+	})
+}
+
+// OrderStatusPublisher moves orders between the status subjects of the ORDERS
+// stream and refreshes the status bucket projection.
+type OrderStatusPublisher struct {
+	js       jetstream.JetStream
+	statusKV jetstream.KeyValue
+	subject  string
+}
+
+func NewOrderStatusPublisher(js jetstream.JetStream, statusKV jetstream.KeyValue, subject string) *OrderStatusPublisher {
+	return &OrderStatusPublisher{js: js, statusKV: statusKV, subject: subject}
+}
+
+// Publish moves the order to the given status subject. The publish is
+// deduplicated per order and status, so a redelivered order never moves to the
+// same status twice in the stream. A cancelled order keeps its status in the
+// bucket whatever is published after the cancellation.
+func (p *OrderStatusPublisher) Publish(ctx context.Context, order Order, status string) error {
+	msg := &nats.Msg{
+		Subject: fmt.Sprintf("%s.%s.%s", p.subject, status, order.OrderID),
+		Header:  nats.Header{},
+	}
+	msg.Header.Set(jetstream.MsgIDHeader, order.OrderID+"."+status)
+
+	order.Status = status
+	if order.Timestamps == nil {
+		order.Timestamps = make(map[string]time.Time)
+	}
+	order.Timestamps[order.Status] = time.Now()
+
+	// Same as telemetry.InjectContextToNatsMsg, which imports this package
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(msg.Header))
+	data, err := json.Marshal(order)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to marshal order to json", slog.Any("err", err))
+		return err
+	}
+
+	msg.Data = data
+
+	_, err = p.js.PublishMsg(ctx, msg)
+	if err != nil {
+		return err
+	}
+
+	err = p.refreshStatus(ctx, order.OrderID, data)
+	if err != nil {
+		// The order already moved forward in the stream, only the lookup projection is stale
+		slog.ErrorContext(ctx, "failed to update order status bucket", slog.String("order-id", order.OrderID), slog.Any("err", err))
+		trace.SpanFromContext(ctx).RecordError(err)
+	}
+
+	return nil
+}
+
+// refreshStatus writes data to the status bucket unless the order there is
+// cancelled. The write only applies to the revision that was checked, so a
+// cancellation written meanwhile is never overwritten.
+func (p *OrderStatusPublisher) refreshStatus(ctx context.Context, orderID string, data []byte) error {
+	var err error
+	for range statusBucketAttempts {
+		var entry jetstream.KeyValueEntry
+		entry, err = p.statusKV.Get(ctx, orderID)
+		if errors.Is(err, jetstream.ErrKeyNotFound) {
+			_, err = p.statusKV.Create(ctx, orderID, data)
+		} else if err == nil {
+			var current Order
+			err = json.Unmarshal(entry.Value(), &current)
+			if err == nil && current.Status == orderStatusCancelled {
+				slog.InfoContext(ctx, "Order was cancelled, keeping its status", slog.String("order-id", orderID))
+				return nil
+			}
+			_, err = p.statusKV.Update(ctx, orderID, data, entry.Revision())
+		}
+		if !errors.Is(err, jetstream.ErrKeyExists) {
+			return err
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,123 @@
+package pacchetto
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeJetStream keeps the messages published.
+type fakeJetStream struct {
+	jetstream.JetStream
+	published []*nats.Msg
+}
+
+func (f *fakeJetStream) PublishMsg(_ context.Context, msg *nats.Msg, _ ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	f.published = append(f.published, msg)
+	return &jetstream.PubAck{}, nil
+}
+
+// fakeKeyValue keeps the latest value of every key.
+type fakeKeyValue struct {
+	jetstream.KeyValue
+	values map[string][]byte
+}
+
+// fakeEntry is a key-value entry whose revision is its value.
+type fakeEntry struct {
+	jetstream.KeyValueEntry
+	value []byte
+}
+
+func (e fakeEntry) Value() []byte    { return e.value }
+func (e fakeEntry) Revision() uint64 { return uint64(len(e.value)) }
+
+func (f *fakeKeyValue) Get(_ context.Context, key string) (jetstream.KeyValueEntry, error) {
+	value, ok := f.values[key]
+	if !ok {
+		return nil, jetstream.ErrKeyNotFound
+	}
+	return fakeEntry{value: value}, nil
+}
+
+func (f *fakeKeyValue) Create(_ context.Context, key string, value []byte, _ ...jetstream.KVCreateOpt) (uint64, error) {
+	if _, ok := f.values[key]; ok {
+		return 0, jetstream.ErrKeyExists
+	}
+	f.values[key] = value
+	return 1, nil
+}
+
+func (f *fakeKeyValue) Update(_ context.Context, key string, value []byte, revision uint64) (uint64, error) {
+	if uint64(len(f.values[key])) != revision {
+		return 0, jetstream.ErrKeyExists
+	}
+	f.values[key] = value
+	return 1, nil
+}
+
+func TestOrderStatusPublisherPublish(t *testing.T) {
+	// Arrange
+	js := &fakeJetStream{}
+	kv := &fakeKeyValue{values: make(map[string][]byte)}
+	publisher := NewOrderStatusPublisher(js, kv, "orders")
+	order := Order{OrderID: "123", Status: "waiting_payment", Username: "charles_leclerc"}
+
+	// Act
+	err := publisher.Publish(t.Context(), order, "waiting_to_cook")
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, js.published, 1)
+	msg := js.published[0]
+	assert.Equal(t, "orders.waiting_to_cook.123", msg.Subject)
+	assert.Equal(t, "123.waiting_to_cook", msg.Header.Get(jetstream.MsgIDHeader))
+	assert.Equal(t, msg.Data, kv.values["123"], "the status bucket has the published order")
+
+	var published Order
+	require.NoError(t, json.Unmarshal(msg.Data, &published))
+	assert.Equal(t, "waiting_to_cook", published.Status)
+	assert.Contains(t, published.Timestamps, "waiting_to_cook")
+	assert.Equal(t, "waiting_payment", order.Status, "the caller's order is left as it was")
+}
+
+func TestOrderStatusPublisherKeepsCancellations(t *testing.T) {
+	tests := []struct {
+		name       string
+		stored     string
+		wantStatus string
+	}{
+		{name: "new order", wantStatus: "waiting_delivery"},
+		{name: "order moving forward", stored: "waiting_to_cook", wantStatus: "waiting_delivery"},
+		{name: "cancelled order", stored: "cancelled", wantStatus: "cancelled"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			js := &fakeJetStream{}
+			kv := &fakeKeyValue{values: make(map[string][]byte)}
+			if tt.stored != "" {
+				data, err := json.Marshal(Order{OrderID: "123", Status: tt.stored})
+				require.NoError(t, err)
+				kv.values["123"] = data
+			}
+			publisher := NewOrderStatusPublisher(js, kv, "orders")
+
+			// Act
+			err := publisher.Publish(t.Context(), Order{OrderID: "123"}, "waiting_delivery")
+
+			// Assert
+			require.NoError(t, err)
+			assert.Len(t, js.published, 1, "the stream always gets the move")
+			var stored Order
+			require.NoError(t, json.Unmarshal(kv.values["123"], &stored))
+			assert.Equal(t, tt.wantStatus, stored.Status)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package pacchetto
+
+import (
+	"context"
+	"sync"
+)
+
+// StatusWatchers fans out the status of a service to the streams watching it,
+// such as the WatchStatus RPCs. Every watcher first receives the current
+// status, then every status published. Publishing never blocks: a watcher
+// still busy with a previous status skips to the latest one.
+type StatusWatchers[T any] struct {
+	mu       sync.Mutex
+	latest   T
+	watchers map[chan T]struct{}
+}
+
+func NewStatusWatchers[T any](initial T) *StatusWatchers[T] {
+	return &StatusWatchers[T]{
+		latest:   initial,
+		watchers: make(map[chan T]struct{}),
+	}
+}
+
+// Publish makes status the current status and sends it to every watcher.
+// Callers publishing from several goroutines must serialize the calls for the
+// watchers to see the statuses in order.
+func (w *StatusWatchers[T]) Publish(status T) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.latest = status
+	for ch := range w.watchers {
+		// Only Publish sends, under mu, so the buffer has room once drained
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- status
+	}
+}
+
+// Watch returns a channel receiving the current status and then every status
+// published, until ctx is done.
+func (w *StatusWatchers[T]) Watch(ctx context.Context) <-chan T {
+	ch := make(chan T, 1)
+
+	w.mu.Lock()
+	ch <- w.latest
+	w.watchers[ch] = struct{}{}
+	w.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		w.mu.Lock()
+		delete(w.watchers, ch)
+		w.mu.Unlock()
+	}()
+
+	return ch
+}
@@ -0,0 +1,26 @@
+package pacchetto
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusWatchers(t *testing.T) {
+	// Arrange
+	watchers := NewStatusWatchers("idle")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Act
+	statuses := watchers.Watch(ctx)
+	initial := <-statuses
+	watchers.Publish("working")
+	watchers.Publish("sleeping")
+
+	// Assert
+	assert.Equal(t, "idle", initial)
+	assert.Equal(t, "sleeping", <-statuses, "a slow watcher skips to the latest status")
+	assert.Empty(t, statuses)
+}
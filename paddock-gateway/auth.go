@@ -0,0 +1,341 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// ContextKeyUserID holds the token subject of an authenticated request.
+	ContextKeyUserID = "user_id"
+	// AttributeUserID is the trace attribute and baggage member carrying the user ID.
+	AttributeUserID = "enduser.id"
+	// QueryParamAccessToken carries the token for clients that cannot set
+	// headers, such as EventSource and browser WebSockets. It is only accepted
+	// on accessTokenRoutes.
+	QueryParamAccessToken = "access_token"
+)
+
+// accessTokenRoutes are the streaming routes accepting QueryParamAccessToken.
+var accessTokenRoutes = map[string]struct{}{
+	"/v1/order/sse":     {},
+	"/v1/order/:id/sse": {},
+	"/v1/ws":            {},
+}
+
+type accessTokenContextKey struct{}
+
+var errUnknownSigningKey = errors.New("unknown signing key")
+
+// jwk is a single JSON Web Key, only the public parts we verify with.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// JWKS is a set of public keys loaded from a file or a URL, indexed by key ID.
+type JWKS struct {
+	source string
+
+	mu   sync.RWMutex
+	keys map[string]crypto.PublicKey
+}
+
+func NewJWKS(ctx context.Context, source string) (*JWKS, error) {
+	jwks := &JWKS{source: source}
+
+	err := jwks.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return jwks, nil
+}
+
+// Load reads the key set again, so rotated keys are picked up.
+func (j *JWKS) Load(ctx context.Context) error {
+	data, err := j.read(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read JWKS from %s: %w", j.source, err)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	err = json.Unmarshal(data, &set)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		key, err := k.publicKey()
+		if err != nil {
+			slog.WarnContext(ctx, "skipping unsupported JWK", slog.String("kid", k.Kid), slog.String("error", err.Error()))
+			continue
+		}
+		keys[k.Kid] = key
+	}
+
+	if len(keys) == 0 {
+		return errors.New("JWKS has no usable keys")
+	}
+
+	j.mu.Lock()
+	j.keys = keys
+	j.mu.Unlock()
+
+	return nil
+}
+
+// Refresh reloads the key set every interval until ctx is done.
+func (j *JWKS) Refresh(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := j.Load(ctx)
+			if err != nil {
+				slog.ErrorContext(ctx, "failed to refresh JWKS, keeping the previous keys", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// Keyfunc implements jwt.Keyfunc. Tokens without a kid are accepted only when
+// the set has a single key.
+func (j *JWKS) Keyfunc(token *jwt.Token) (any, error) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" && len(j.keys) == 1 {
+		for _, key := range j.keys {
+			return key, nil
+		}
+	}
+
+	key, ok := j.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", errUnknownSigningKey, kid)
+	}
+
+	return key, nil
+}
+
+func (j *JWKS) read(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(j.source, "http://") && !strings.HasPrefix(j.source, "https://") {
+		return os.ReadFile(j.source)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.source, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// NewJWTMiddleware rejects requests without a valid bearer token and stores the
+// token subject as the user ID of the request, in the echo context, the
+// current span and the baggage propagated to downstream services.
+func NewJWTMiddleware(jwks *JWKS, settings AuthSettings) echo.MiddlewareFunc {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Duration(settings.LeewayInSeconds) * time.Second),
+	}
+	if settings.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(settings.Issuer))
+	}
+	if settings.Audience != "" {
+		opts = append(opts, jwt.WithAudience(settings.Audience))
+	}
+	parser := jwt.NewParser(opts...)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := req.Context()
+
+			raw, ok := bearerToken(c)
+			if !ok {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+				return writeProblem(c, newProblem(c, http.StatusUnauthorized, "A bearer token is required"))
+			}
+
+			token, err := parser.Parse(raw, jwks.Keyfunc)
+			if err != nil {
+				slog.InfoContext(ctx, "rejected invalid token", slog.String("error", err.Error()))
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+				return writeProblem(c, newProblem(c, http.StatusUnauthorized, "The bearer token is invalid"))
+			}
+
+			userID, err := token.Claims.GetSubject()
+			if err != nil || userID == "" {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+				return writeProblem(c, newProblem(c, http.StatusUnauthorized, "The bearer token has no subject"))
+			}
+
+			trace.SpanFromContext(ctx).SetAttributes(attribute.String(AttributeUserID, userID))
+			member, err := baggage.NewMemberRaw(AttributeUserID, userID)
+			if err == nil {
+				bag, err := baggage.FromContext(ctx).SetMember(member)
+				if err == nil {
+					ctx = baggage.ContextWithBaggage(ctx, bag)
+				}
+			}
+
+			c.SetRequest(req.WithContext(ctx))
+			c.Set(ContextKeyUserID, userID)
+
+			return next(c)
+		}
+	}
+}
+
+func bearerToken(c echo.Context) (string, bool) {
+	scheme, token, ok := strings.Cut(c.Request().Header.Get(echo.HeaderAuthorization), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") && token != "" {
+		return token, true
+	}
+
+	if _, ok := accessTokenRoutes[c.Path()]; !ok {
+		return "", false
+	}
+	token, _ = c.Request().Context().Value(accessTokenContextKey{}).(string)
+	return token, token != ""
+}
+
+// redactAccessToken moves QueryParamAccessToken from the URL to the request
+// context before anything logs or traces the URL. It must run before every
+// other middleware, with echo.Pre.
+func redactAccessToken(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		query := req.URL.Query()
+		if !query.Has(QueryParamAccessToken) {
+			return next(c)
+		}
+
+		token := query.Get(QueryParamAccessToken)
+		query.Set(QueryParamAccessToken, "redacted")
+
+		redacted := req.WithContext(context.WithValue(req.Context(), accessTokenContextKey{}, token))
+		redactedURL := *req.URL
+		redactedURL.RawQuery = query.Encode()
+		redacted.URL = &redactedURL
+		redacted.RequestURI = redactedURL.RequestURI()
+		c.SetRequest(redacted)
+
+		return next(c)
+	}
+}
+
+// authenticatedUser returns the user ID set by the JWT middleware, if any.
+func authenticatedUser(c echo.Context) (string, bool) {
+	userID, ok := c.Get(ContextKeyUserID).(string)
+	return userID, ok && userID != ""
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestJWKS(t *testing.T, kid string, key *rsa.PublicKey) string {
+	t.Helper()
+
+	set := map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": kid,
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	}
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+func signTestToken(t *testing.T, key *rsa.PrivateKey, claims jwt.RegisteredClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "paddock"
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestJWTMiddlewareOrderNewPizza(t *testing.T) {
+	// Arrange
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwks, err := NewJWKS(context.Background(), writeTestJWKS(t, "paddock", &key.PublicKey))
+	require.NoError(t, err)
+
+	authSettings := AuthSettings{Issuer: "scuderia"}
+	e := echo.New()
+	pubSubber := NewGoChannelOrderPubSubber()
+	NewMainHandler(e, &Settings{Auth: authSettings}, MainHandlerDeps{OrderPubSubber: pubSubber, OrderGetter: pubSubber, Auth: NewJWTMiddleware(jwks, authSettings)})
+
+	sign := func(signingKey *rsa.PrivateKey, claims jwt.RegisteredClaims) string {
+		return signTestToken(t, signingKey, claims)
+	}
+	valid := jwt.RegisteredClaims{
+		Subject:   "charles_leclerc",
+		Issuer:    "scuderia",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}
+	expired := valid
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+
+	tests := []struct {
+		name       string
+		token      string
+		username   string
+		wantStatus int
+	}{
+		{
+			name:       "username derived from the token",
+			token:      sign(key, valid),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "matching username",
+			token:      sign(key, valid),
+			username:   "charles_leclerc",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "ordering for someone else",
+			token:      sign(key, valid),
+			username:   "carlos_sainz",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "missing token",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "expired token",
+			token:      sign(key, expired),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "token signed by an unknown key",
+			token:      sign(otherKey, valid),
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"size":"large","toppings":["pepperoni"],"destination":"Garage #16","username":"` + tt.username + `"}`
+			req := httptest.NewRequest(http.MethodPost, "/v1/order", strings.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			if tt.token != "" {
+				req.Header.Set(echo.HeaderAuthorization, "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+
+			// Act
+			e.ServeHTTP(rec, req)
+
+			// Assert
+			assert.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+		})
+	}
+}
+
+func TestJWTMiddlewareGetOrder(t *testing.T) {
+	// Arrange
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwks, err := NewJWKS(context.Background(), writeTestJWKS(t, "paddock", &key.PublicKey))
+	require.NoError(t, err)
+
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	authSettings := AuthSettings{}
+	e := echo.New()
+	pubSubber := NewGoChannelOrderPubSubber()
+	NewMainHandler(e, &Settings{Auth: authSettings}, MainHandlerDeps{OrderPubSubber: pubSubber, OrderGetter: pubSubber, Auth: NewJWTMiddleware(jwks, authSettings)})
+	require.NoError(t, pubSubber.PubOrder(context.Background(), Order{OrderID: "order-16", Username: "charles_leclerc", Status: OrderStatusWaitingToCook}))
+
+	tokenFor := func(subject string) string {
+		return signTestToken(t, key, jwt.RegisteredClaims{
+			Subject:   subject,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		})
+	}
+
+	tests := []struct {
+		name        string
+		target      string
+		bearer      string
+		accessToken string
+		wantStatus  int
+	}{
+		{
+			name:       "own order",
+			target:     "/v1/order/order-16",
+			bearer:     tokenFor("charles_leclerc"),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "order of another customer",
+			target:     "/v1/order/order-16",
+			bearer:     tokenFor("carlos_sainz"),
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:        "access token on a route that is not a stream",
+			target:      "/v1/order/order-16",
+			accessToken: tokenFor("charles_leclerc"),
+			wantStatus:  http.StatusUnauthorized,
+		},
+		{
+			name:        "stream of the order of another customer",
+			target:      "/v1/order/order-16/sse",
+			accessToken: tokenFor("carlos_sainz"),
+			wantStatus:  http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := tt.target
+			if tt.accessToken != "" {
+				target += "?" + QueryParamAccessToken + "=" + tt.accessToken
+			}
+			req := httptest.NewRequest(http.MethodGet, target, nil)
+			if tt.bearer != "" {
+				req.Header.Set(echo.HeaderAuthorization, "Bearer "+tt.bearer)
+			}
+			rec := httptest.NewRecorder()
+
+			// Act
+			e.ServeHTTP(rec, req)
+
+			// Assert
+			assert.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			if tt.accessToken != "" {
+				assert.NotContains(t, logs.String(), tt.accessToken, "access tokens must not be logged")
+			}
+		})
+	}
+}
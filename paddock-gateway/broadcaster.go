@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var meter = otel.Meter("paddock-gateway")
+
+// SlowConsumerPolicy decides what happens to a subscriber whose buffer is full.
+type SlowConsumerPolicy string
+
+const (
+	// SlowConsumerDrop discards the new event for that subscriber.
+	SlowConsumerDrop SlowConsumerPolicy = "drop"
+	// SlowConsumerDisconnect closes the subscription, the client is expected to
+	// reconnect with Last-Event-ID.
+	SlowConsumerDisconnect SlowConsumerPolicy = "disconnect"
+	// SlowConsumerCoalesce drops the buffered event of the same order, or the
+	// oldest one, so the subscriber always ends up with the latest state.
+	SlowConsumerCoalesce SlowConsumerPolicy = "coalesce"
+)
+
+type subscriber struct {
+	mu      sync.Mutex
+	ch      chan OrderEvent
+	orderID string
+	// live subscribers are fed by Publish, the others by Offer from a replay
+	// until it is attached.
+	live bool
+	// after is the last sequence replayed, Publish skips the events up to it.
+	after  uint64
+	closed bool
+}
+
+// Broadcaster fans out order events to subscribers without ever blocking the
+// publisher: every subscriber has a bounded buffer and a slow consumer is
+// handled according to the configured policy.
+type Broadcaster struct {
+	bufferSize int
+	policy     SlowConsumerPolicy
+
+	mu   sync.RWMutex
+	subs map[http.Flusher]*subscriber
+	// lastSequence is the sequence of the last event published, written under
+	// the read lock and checked by Attach under the write lock.
+	lastSequence atomic.Uint64
+
+	connections metric.Int64UpDownCounter
+	dropped     metric.Int64Counter
+}
+
+func NewBroadcaster(bufferSize int, policy SlowConsumerPolicy) (*Broadcaster, error) {
+	ctx := context.Background()
+
+	connections, err := meter.Int64UpDownCounter(
+		"paddock_gateway.sse.connections",
+		metric.WithDescription("Number of connected live order subscribers"),
+		metric.WithUnit("{connection}"),
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to create connections counter", slog.Any("err", err))
+		return nil, err
+	}
+
+	dropped, err := meter.Int64Counter(
+		"paddock_gateway.sse.dropped_events",
+		metric.WithDescription("Number of order events not delivered to slow subscribers"),
+		metric.WithUnit("{event}"),
+	)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to create dropped events counter", slog.Any("err", err))
+		return nil, err
+	}
+
+	return &Broadcaster{
+		bufferSize:  bufferSize,
+		policy:      policy,
+		subs:        make(map[http.Flusher]*subscriber),
+		connections: connections,
+		dropped:     dropped,
+	}, nil
+}
+
+// Subscribe registers a subscriber under key. Live subscribers receive every
+// published event, or only the ones of orderID when it is set. The returned
+// channel is closed on Unsubscribe or when the subscriber is disconnected for
+// being too slow.
+func (b *Broadcaster) Subscribe(ctx context.Context, key http.Flusher, orderID string, live bool) <-chan OrderEvent {
+	sub := &subscriber{
+		ch:      make(chan OrderEvent, b.bufferSize),
+		orderID: orderID,
+		live:    live,
+	}
+
+	b.mu.Lock()
+	previous, ok := b.subs[key]
+	b.subs[key] = sub
+	b.mu.Unlock()
+
+	if ok {
+		previous.close()
+	} else {
+		b.connections.Add(ctx, 1)
+	}
+
+	return sub.ch
+}
+
+// Unsubscribe removes the subscriber under key and closes its channel.
+func (b *Broadcaster) Unsubscribe(ctx context.Context, key http.Flusher) {
+	b.mu.Lock()
+	sub, ok := b.subs[key]
+	delete(b.subs, key)
+	b.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	sub.close()
+	b.connections.Add(ctx, -1)
+}
+
+// Publish delivers event to every matching live subscriber.
+func (b *Broadcaster) Publish(ctx context.Context, event OrderEvent) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	b.lastSequence.Store(event.Sequence)
+	for _, sub := range b.subs {
+		if !sub.live || (sub.orderID != "" && sub.orderID != event.Order.OrderID) {
+			continue
+		}
+		if event.Sequence <= sub.after {
+			continue
+		}
+		b.deliver(ctx, sub, event)
+	}
+}
+
+// Attach makes the replayed subscriber under key live once the replay went
+// through sequence. It reports false while events after sequence were already
+// published: the replay must deliver them first and try again.
+func (b *Broadcaster) Attach(key http.Flusher, sequence uint64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.lastSequence.Load() > sequence {
+		return false
+	}
+
+	sub, ok := b.subs[key]
+	if ok {
+		sub.live = true
+		sub.after = sequence
+	}
+	return true
+}
+
+// Offer delivers event to the subscriber under key only, used by replays.
+func (b *Broadcaster) Offer(ctx context.Context, key http.Flusher, event OrderEvent) {
+	b.mu.RLock()
+	sub, ok := b.subs[key]
+	b.mu.RUnlock()
+
+	if ok {
+		b.deliver(ctx, sub, event)
+	}
+}
+
+func (b *Broadcaster) deliver(ctx context.Context, sub *subscriber, event OrderEvent) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	if sub.closed {
+		return
+	}
+
+	select {
+	case sub.ch <- event:
+		return
+	default:
+	}
+
+	b.dropped.Add(ctx, 1, metric.WithAttributes(attribute.String("policy", string(b.policy))))
+
+	switch b.policy {
+	case SlowConsumerDisconnect:
+		slog.WarnContext(ctx, "disconnecting slow subscriber")
+		sub.closed = true
+		close(sub.ch)
+	case SlowConsumerCoalesce:
+		sub.coalesce(event)
+	default:
+		slog.DebugContext(ctx, "dropping event for slow subscriber", slog.String("order_id", event.Order.OrderID))
+	}
+}
+
+// coalesce makes room for event in a full buffer. Must be called with sub.mu held.
+func (s *subscriber) coalesce(event OrderEvent) {
+	// Only the publisher side sends, so everything drained here can be put back
+	// even if the reader takes some events meanwhile.
+	queued := make([]OrderEvent, 0, cap(s.ch))
+drain:
+	for {
+		select {
+		case e := <-s.ch:
+			queued = append(queued, e)
+		default:
+			break drain
+		}
+	}
+
+	stale := 0
+	for i, e := range queued {
+		if e.Order.OrderID == event.Order.OrderID {
+			stale = i
+			break
+		}
+	}
+	if len(queued) > 0 && len(queued) == cap(s.ch) {
+		queued = append(queued[:stale], queued[stale+1:]...)
+	}
+
+	for _, e := range append(queued, event) {
+		s.ch <- e
+	}
+}
+
+func (s *subscriber) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type nopFlusher struct{ name string }
+
+func (*nopFlusher) Flush() {}
+
+func drainEvents(ch <-chan OrderEvent) (sequences []uint64, closed bool) {
+	for {
+		select {
+		case event, ok := <-ch:
+			if !ok {
+				return sequences, true
+			}
+			sequences = append(sequences, event.Sequence)
+		default:
+			return sequences, false
+		}
+	}
+}
+
+func TestBroadcasterSlowConsumerPolicies(t *testing.T) {
+	events := []OrderEvent{
+		{Order: Order{OrderID: "a"}, Sequence: 1},
+		{Order: Order{OrderID: "b"}, Sequence: 2},
+		{Order: Order{OrderID: "a"}, Sequence: 3},
+	}
+
+	tests := []struct {
+		name          string
+		policy        SlowConsumerPolicy
+		wantSequences []uint64
+		wantClosed    bool
+	}{
+		{
+			name:          "drop keeps the buffered events",
+			policy:        SlowConsumerDrop,
+			wantSequences: []uint64{1, 2},
+		},
+		{
+			name:          "disconnect closes the subscription",
+			policy:        SlowConsumerDisconnect,
+			wantSequences: []uint64{1, 2},
+			wantClosed:    true,
+		},
+		{
+			name:          "coalesce keeps the latest state of each order",
+			policy:        SlowConsumerCoalesce,
+			wantSequences: []uint64{2, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			broadcaster, err := NewBroadcaster(2, tt.policy)
+			require.NoError(t, err)
+			ch := broadcaster.Subscribe(ctx, &nopFlusher{}, "", true)
+
+			// Act
+			for _, event := range events {
+				broadcaster.Publish(ctx, event)
+			}
+			sequences, closed := drainEvents(ch)
+
+			// Assert
+			assert.Equal(t, tt.wantSequences, sequences)
+			assert.Equal(t, tt.wantClosed, closed)
+		})
+	}
+}
+
+func TestBroadcasterRouting(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	broadcaster, err := NewBroadcaster(8, SlowConsumerDrop)
+	require.NoError(t, err)
+
+	dashboard := &nopFlusher{name: "dashboard"}
+	customer := &nopFlusher{name: "customer"}
+	replay := &nopFlusher{name: "replay"}
+	dashboardCh := broadcaster.Subscribe(ctx, dashboard, "", true)
+	customerCh := broadcaster.Subscribe(ctx, customer, "b", true)
+	replayCh := broadcaster.Subscribe(ctx, replay, "", false)
+
+	// Act
+	broadcaster.Publish(ctx, OrderEvent{Order: Order{OrderID: "a"}, Sequence: 1})
+	broadcaster.Publish(ctx, OrderEvent{Order: Order{OrderID: "b"}, Sequence: 2})
+	broadcaster.Offer(ctx, replay, OrderEvent{Order: Order{OrderID: "a"}, Sequence: 1})
+	broadcaster.Unsubscribe(ctx, dashboard)
+	broadcaster.Publish(ctx, OrderEvent{Order: Order{OrderID: "b"}, Sequence: 3})
+
+	dashboardSequences, dashboardClosed := drainEvents(dashboardCh)
+	customerSequences, _ := drainEvents(customerCh)
+	replaySequences, _ := drainEvents(replayCh)
+
+	// Assert
+	assert.Equal(t, []uint64{1, 2}, dashboardSequences)
+	assert.True(t, dashboardClosed)
+	assert.Equal(t, []uint64{2, 3}, customerSequences)
+	assert.Equal(t, []uint64{1}, replaySequences)
+}
+
+func TestBroadcasterAttach(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	broadcaster, err := NewBroadcaster(8, SlowConsumerDrop)
+	require.NoError(t, err)
+
+	behind := &nopFlusher{name: "behind"}
+	ahead := &nopFlusher{name: "ahead"}
+	behindCh := broadcaster.Subscribe(ctx, behind, "a", false)
+	aheadCh := broadcaster.Subscribe(ctx, ahead, "a", false)
+
+	// Act
+	broadcaster.Publish(ctx, OrderEvent{Order: Order{OrderID: "a"}, Sequence: 1})
+	broadcaster.Publish(ctx, OrderEvent{Order: Order{OrderID: "a"}, Sequence: 2})
+
+	broadcaster.Offer(ctx, behind, OrderEvent{Order: Order{OrderID: "a"}, Sequence: 1})
+	attachedEarly := broadcaster.Attach(behind, 1)
+	broadcaster.Offer(ctx, behind, OrderEvent{Order: Order{OrderID: "a"}, Sequence: 2})
+	attached := broadcaster.Attach(behind, 2)
+
+	// The replay read further than the live consumer delivered so far
+	broadcaster.Offer(ctx, ahead, OrderEvent{Order: Order{OrderID: "a"}, Sequence: 3})
+	attachedAhead := broadcaster.Attach(ahead, 3)
+
+	broadcaster.Publish(ctx, OrderEvent{Order: Order{OrderID: "a"}, Sequence: 3})
+	broadcaster.Publish(ctx, OrderEvent{Order: Order{OrderID: "b"}, Sequence: 4})
+	broadcaster.Publish(ctx, OrderEvent{Order: Order{OrderID: "a"}, Sequence: 5})
+
+	behindSequences, _ := drainEvents(behindCh)
+	aheadSequences, _ := drainEvents(aheadCh)
+
+	// Assert
+	assert.False(t, attachedEarly, "events after the replay were published already")
+	assert.True(t, attached)
+	assert.True(t, attachedAhead)
+	assert.Equal(t, []uint64{1, 2, 3, 5}, behindSequences)
+	assert.Equal(t, []uint64{3, 5}, aheadSequences)
+}
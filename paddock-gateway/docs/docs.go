@@ -5,7 +5,7 @@ package docs
 import "github.com/swaggo/swag/v2"
 
 const docTemplate = `{
-    "schemes": {{ marshal .Schemes }},"swagger":"2.0","info":{"description":"{{escape .Description}}","title":"{{.Title}}","contact":{},"version":"{{.Version}}"},"host":"{{.Host}}","basePath":"{{.BasePath}}","paths":{"/healthz":{"get":{"produces":["application/json"],"tags":["health"],"summary":"Check the health of the service","responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/health.Check"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/health.Check"}}}}},"/v1/order":{"post":{"consumes":["application/json"],"produces":["application/json"],"tags":["order"],"summary":"Create a new pizza order","parameters":[{"description":"New Pizza Order Request","name":"order","in":"body","required":true,"schema":{"$ref":"#/definitions/main.NewPizzaOrderRequest"}}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/main.NewPizzaOrderResponse"}},"422":{"description":"error","schema":{"type":"string"}}}}},"/v1/orders/sse":{"get":{"produces":["text/event-stream"],"tags":["order"],"summary":"Get live orders via Server-Sent Events (SSE)","responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/main.Order"}}}}}},"definitions":{"health.Check":{"type":"object","properties":{"component":{"description":"Component holds information on the component for which checks are made","allOf":[{"$ref":"#/definitions/health.Component"}]},"failures":{"description":"Failures holds the failed checks along with their messages.","type":"object","additionalProperties":{"type":"string"}},"status":{"description":"Status is the check status.","allOf":[{"$ref":"#/definitions/health.Status"}]},"system":{"description":"System holds information of the go process.","allOf":[{"$ref":"#/definitions/health.System"}]},"timestamp":{"description":"Timestamp is the time in which the check occurred.","type":"string"}}},"health.Component":{"type":"object","properties":{"name":{"description":"Name is the name of the component.","type":"string"},"version":{"description":"Version is the component version.","type":"string"}}},"health.Status":{"type":"string","enum":["OK","Partially Available","Unavailable","Timeout during health check"],"x-enum-varnames":["StatusOK","StatusPartiallyAvailable","StatusUnavailable","StatusTimeout"]},"health.System":{"type":"object","properties":{"alloc_bytes":{"description":"TotalAllocBytes is the bytes allocated and not yet freed.","type":"integer"},"goroutines_count":{"description":"GoroutinesCount is the number of the current goroutines.","type":"integer"},"heap_objects_count":{"description":"HeapObjectsCount is the number of objects in the go heap.","type":"integer"},"total_alloc_bytes":{"description":"TotalAllocBytes is the total bytes allocated.","type":"integer"},"version":{"description":"Version is the go version.","type":"string"}}},"main.NewPizzaOrderRequest":{"type":"object","required":["destination","size","toppings","username"],"properties":{"destination":{"type":"string"},"size":{"type":"string","enum":["small","medium","large"]},"toppings":{"type":"array","items":{"type":"string"}},"username":{"type":"string"}}},"main.NewPizzaOrderResponse":{"type":"object","properties":{"order_id":{"type":"string"},"ordered_at":{"type":"string"}}},"main.Order":{"type":"object","properties":{"destination":{"type":"string"},"order_id":{"type":"string"},"ordered_at":{"type":"string"},"size":{"type":"string"},"status":{"description":"e.g., \"pending\", \"in_progress\", \"completed\"","type":"string"},"toppings":{"type":"array","items":{"type":"string"}},"username":{"type":"string"}}}},"securityDefinitions":{"Bearer":{"description":"Type \"Bearer\" followed by a space and JWT token.","type":"apiKey","name":"Authorization","in":"header"}}}`
+    "schemes": {{ marshal .Schemes }},"swagger":"2.0","info":{"description":"{{escape .Description}}","title":"{{.Title}}","contact":{},"version":"{{.Version}}"},"host":"{{.Host}}","basePath":"{{.BasePath}}","paths":{"/healthz":{"get":{"produces":["application/json"],"tags":["health"],"summary":"Check the health of the service","responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/health.Check"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/health.Check"}}}}},"/v1/menu":{"get":{"security":[{"Bearer":[]}],"description":"Items with available false are sold out and rejected with 422 when ordered.","produces":["application/json"],"tags":["menu"],"summary":"Get the sizes, borders and toppings that can be ordered","responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/main.Menu"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/main.ProblemDetails"}}}}},"/v1/order":{"post":{"security":[{"Bearer":[]}],"description":"Order several pizzas at once with items, or a single pizza with size, border and toppings.\nSend an Idempotency-Key header to safely retry: a repeated key replays the original response.\nWhen the order cannot be published it is kept in the outbox and answered with 202, or,\nwithout an outbox, answered with 503 and a Retry-After header.\nWith authentication enabled the username is the token subject, a different username is rejected with 403.\nSizes, borders and toppings must be on the menu and available, otherwise the order is rejected with 422.\nOrders are rate limited per user and per client IP, see the RateLimit headers; over the limit the answer is 429.\nA promo code takes its discount off the total; an unknown, expired or used up code is rejected with 422.","consumes":["application/json"],"produces":["application/json"],"tags":["order"],"summary":"Create a new pizza order","parameters":[{"type":"string","description":"Client generated key that identifies this order attempt","name":"Idempotency-Key","in":"header"},{"description":"New Pizza Order Request","name":"order","in":"body","required":true,"schema":{"$ref":"#/definitions/main.NewPizzaOrderRequest"}}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/main.NewPizzaOrderResponse"}},"202":{"description":"Accepted","schema":{"$ref":"#/definitions/main.NewPizzaOrderResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/main.ProblemDetails"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/main.ProblemDetails"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/main.ProblemDetails"}},"422":{"description":"Unprocessable Entity","schema":{"$ref":"#/definitions/main.ProblemDetails"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/main.ProblemDetails"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/main.ProblemDetails"}}}}},"/v1/order/quote":{"post":{"security":[{"Bearer":[]}],"description":"Prices are in minor units of the currency, cents for EUR.\nA promo code is checked and its discount applied, but it is only redeemed by placing the order.","consumes":["application/json"],"produces":["application/json"],"tags":["order"],"summary":"Price pizzas without ordering them","parameters":[{"description":"Pizzas to price","name":"quote","in":"body","required":true,"schema":{"$ref":"#/definitions/main.QuoteRequest"}}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/main.QuoteResponse"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/main.ProblemDetails"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/main.ProblemDetails"}},"422":{"description":"Unprocessable Entity","schema":{"$ref":"#/definitions/main.ProblemDetails"}},"503":{"description":"Service Unavailable","schema":{"$ref":"#/definitions/main.ProblemDetails"}}}}},"/v1/order/sse":{"get":{"security":[{"Bearer":[]}],"description":"Every event id is the stream sequence of the order update. Reconnecting with a\nLast-Event-ID header resumes right after that event.","produces":["text/event-stream"],"tags":["order"],"summary":"Get live orders via Server-Sent Events (SSE)","parameters":[{"type":"string","description":"Resume after this event id","name":"Last-Event-ID","in":"header"},{"enum":["new","all","since"],"type":"string","default":"new","description":"Where the stream starts","name":"deliver","in":"query"},{"type":"string","description":"RFC 3339 timestamp to replay from, implies deliver=since","name":"since","in":"query"}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/main.Order"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/main.ProblemDetails"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/main.ProblemDetails"}}}}},"/v1/order/{id}":{"get":{"security":[{"Bearer":[]}],"produces":["application/json"],"tags":["order"],"summary":"Get the current status of an order","parameters":[{"type":"string","description":"Order ID","name":"id","in":"path","required":true}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/main.Order"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/main.ProblemDetails"}},"404":{"description":"Not Found","schema":{"$ref":"#/definitions/main.ProblemDetails"}}}},"delete":{"security":[{"Bearer":[]}],"description":"The cancellation is asynchronous: orders still waiting for payment are dropped by caixa and\norders still waiting to cook by maestro, while orders whose dough is already being made get a cancelled_after_prep event.\nThe cancelled status is final, later events never replace it.","produces":["application/json"],"tags":["order"],"summary":"Cancel an order that was not sent to delivery yet","parameters":[{"type":"string","description":"Order ID","name":"id","in":"path","required":true}],"responses":{"202":{"description":"Accepted","schema":{"$ref":"#/definitions/main.Order"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/main.ProblemDetails"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/main.ProblemDetails"}},"404":{"description":"Not Found","schema":{"$ref":"#/definitions/main.ProblemDetails"}},"409":{"description":"Conflict","schema":{"$ref":"#/definitions/main.ProblemDetails"}},"429":{"description":"Too Many Requests","schema":{"$ref":"#/definitions/main.ProblemDetails"}}}}},"/v1/order/{id}/receipt":{"get":{"security":[{"Bearer":[]}],"description":"Prices are the ones the order was placed with, in minor units of the currency.\nSend Accept: text/plain for a printable receipt.","produces":["application/json","text/plain"],"tags":["order"],"summary":"Get the itemised receipt of an order","parameters":[{"type":"string","description":"Order ID","name":"id","in":"path","required":true}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/main.Receipt"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/main.ProblemDetails"}},"403":{"description":"Forbidden","schema":{"$ref":"#/definitions/main.ProblemDetails"}},"404":{"description":"Not Found","schema":{"$ref":"#/definitions/main.ProblemDetails"}}}}},"/v1/order/{id}/sse":{"get":{"security":[{"Bearer":[]}],"description":"Sends one event per status transition of the order, starting from the first one,\nand closes the stream once the order reaches a terminal status.\nWith deliver=new, an order already in a terminal status gets that status without an id and the stream closes.\nReconnecting with a Last-Event-ID header resumes right after that event.","produces":["text/event-stream"],"tags":["order"],"summary":"Track a single order via Server-Sent Events (SSE)","parameters":[{"type":"string","description":"Order ID","name":"id","in":"path","required":true},{"type":"string","description":"Resume after this event id","name":"Last-Event-ID","in":"header"},{"enum":["new","all","since"],"type":"string","default":"all","description":"Where the stream starts","name":"deliver","in":"query"},{"type":"string","description":"RFC 3339 timestamp to replay from, implies deliver=since","name":"since","in":"query"}],"responses":{"200":{"description":"OK","schema":{"$ref":"#/definitions/main.Order"}},"400":{"description":"Bad Request","schema":{"$ref":"#/definitions/main.ProblemDetails"}},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/main.ProblemDetails"}},"404":{"description":"Not Found","schema":{"$ref":"#/definitions/main.ProblemDetails"}}}}},"/v1/ws":{"get":{"security":[{"Bearer":[]}],"description":"Send {\"type\":\"subscribe\",\"filter\":{\"statuses\":[\"waiting_to_cook\"],\"usernames\":[\"charles_leclerc\"]}}\nto receive live orders as {\"type\":\"order\",\"sequence\":42,\"order\":{...}} and {\"type\":\"unsubscribe\"} to stop.\nSending subscribe again replaces the filter.","tags":["order"],"summary":"Bidirectional order feed for kitchen dashboards over WebSocket","responses":{"101":{"description":"Switching Protocols"},"401":{"description":"Unauthorized","schema":{"$ref":"#/definitions/main.ProblemDetails"}}}}}},"definitions":{"health.Check":{"type":"object","properties":{"component":{"description":"Component holds information on the component for which checks are made","allOf":[{"$ref":"#/definitions/health.Component"}]},"failures":{"description":"Failures holds the failed checks along with their messages.","type":"object","additionalProperties":{"type":"string"}},"status":{"description":"Status is the check status.","allOf":[{"$ref":"#/definitions/health.Status"}]},"system":{"description":"System holds information of the go process.","allOf":[{"$ref":"#/definitions/health.System"}]},"timestamp":{"description":"Timestamp is the time in which the check occurred.","type":"string"}}},"health.Component":{"type":"object","properties":{"name":{"description":"Name is the name of the component.","type":"string"},"version":{"description":"Version is the component version.","type":"string"}}},"health.Status":{"type":"string","enum":["OK","Partially Available","Unavailable","Timeout during health check"],"x-enum-varnames":["StatusOK","StatusPartiallyAvailable","StatusUnavailable","StatusTimeout"]},"health.System":{"type":"object","properties":{"alloc_bytes":{"description":"TotalAllocBytes is the bytes allocated and not yet freed.","type":"integer"},"goroutines_count":{"description":"GoroutinesCount is the number of the current goroutines.","type":"integer"},"heap_objects_count":{"description":"HeapObjectsCount is the number of objects in the go heap.","type":"integer"},"total_alloc_bytes":{"description":"TotalAllocBytes is the total bytes allocated.","type":"integer"},"version":{"description":"Version is the go version.","type":"string"}}},"main.FieldError":{"type":"object","properties":{"field":{"type":"string"},"message":{"type":"string"},"rule":{"type":"string"}}},"main.Menu":{"type":"object","required":["borders","sizes","toppings"],"properties":{"borders":{"type":"array","minItems":1,"items":{"$ref":"#/definitions/main.MenuItem"}},"sizes":{"type":"array","minItems":1,"items":{"$ref":"#/definitions/main.MenuItem"}},"toppings":{"type":"array","minItems":1,"items":{"$ref":"#/definitions/main.MenuItem"}}}},"main.MenuItem":{"type":"object","required":["name"],"properties":{"allergens":{"type":"array","items":{"type":"string"}},"available":{"type":"boolean"},"name":{"type":"string"}}},"main.NewPizzaOrderItem":{"type":"object","required":["size","toppings"],"properties":{"border":{"description":"Defaults to none","type":"string","enum":["none","cream_cheese","cheddar","chocolate"]},"quantity":{"description":"Defaults to 1","type":"integer","maximum":20,"minimum":1},"size":{"type":"string","enum":["small","medium","large"]},"toppings":{"type":"array","minItems":1,"items":{"type":"string"}}}},"main.NewPizzaOrderRequest":{"type":"object","required":["destination","toppings","username"],"properties":{"border":{"description":"Defaults to none","type":"string","enum":["none","cream_cheese","cheddar","chocolate"]},"destination":{"type":"string"},"items":{"type":"array","maxItems":20,"minItems":1,"items":{"$ref":"#/definitions/main.NewPizzaOrderItem"}},"promo_code":{"type":"string","maxLength":64},"size":{"type":"string","enum":["small","medium","large"]},"toppings":{"type":"array","minItems":1,"items":{"type":"string"}},"username":{"type":"string"}}},"main.NewPizzaOrderResponse":{"type":"object","properties":{"currency":{"type":"string"},"discount":{"type":"integer"},"order_id":{"type":"string"},"ordered_at":{"type":"string"},"total":{"type":"integer"}}},"main.Order":{"type":"object","properties":{"border":{"type":"string"},"currency":{"description":"Prices are in minor units of the currency","type":"string"},"destination":{"type":"string"},"discount":{"description":"Taken off the subtotal by the promo code","type":"integer"},"items":{"type":"array","items":{"$ref":"#/definitions/pacchetto.OrderItem"}},"order_id":{"type":"string"},"ordered_at":{"type":"string"},"payment_id":{"description":"Set by caixa once the order is paid","type":"string"},"promo_code":{"type":"string"},"reason":{"description":"Why the payment failed or the kitchen rejected the order","type":"string"},"rider":{"description":"Set by corriere once the order leaves the pizzeria","type":"string"},"size":{"description":"Single pizza of orders placed before line items, see NormalizeItems","type":"string"},"status":{"description":"e.g., \"waiting_payment\", \"waiting_to_cook\", \"waiting_delivery\", \"delivered\"","type":"string"},"subtotal":{"type":"integer"},"timestamps":{"description":"When the order entered each status","type":"object","additionalProperties":{"type":"string"}},"toppings":{"type":"array","items":{"type":"string"}},"total":{"type":"integer"},"username":{"type":"string"}}},"main.OrderItem":{"type":"object","properties":{"border":{"type":"string"},"prepared":{"description":"Pizzas maestro already baked for this item","type":"integer"},"quantity":{"type":"integer"},"size":{"type":"string"},"toppings":{"type":"array","items":{"type":"string"}},"total":{"type":"integer"},"unit_price":{"type":"integer"}}},"main.ProblemDetails":{"type":"object","properties":{"detail":{"type":"string"},"errors":{"type":"array","items":{"$ref":"#/definitions/main.FieldError"}},"instance":{"type":"string"},"status":{"type":"integer"},"title":{"type":"string"},"type":{"type":"string"}}},"main.QuoteRequest":{"type":"object","required":["items"],"properties":{"items":{"type":"array","maxItems":20,"minItems":1,"items":{"$ref":"#/definitions/main.NewPizzaOrderItem"}},"promo_code":{"type":"string","maxLength":64},"username":{"description":"Username checks the promo code per user limit, the token subject is used with authentication","type":"string"}}},"main.QuoteResponse":{"type":"object","properties":{"currency":{"description":"Prices are in minor units of the currency","type":"string"},"discount":{"type":"integer"},"items":{"type":"array","items":{"$ref":"#/definitions/main.OrderItem"}},"promo_code":{"type":"string"},"subtotal":{"type":"integer"},"total":{"type":"integer"}}},"main.Receipt":{"type":"object","properties":{"currency":{"description":"Prices are in minor units of the currency","type":"string"},"destination":{"type":"string"},"discount":{"type":"integer"},"lines":{"type":"array","items":{"$ref":"#/definitions/main.ReceiptLine"}},"order_id":{"type":"string"},"ordered_at":{"type":"string"},"promo_code":{"type":"string"},"status":{"type":"string"},"subtotal":{"type":"integer"},"total":{"type":"integer"},"username":{"type":"string"}}},"main.ReceiptLine":{"type":"object","properties":{"description":{"type":"string"},"quantity":{"type":"integer"},"total":{"type":"integer"},"unit_price":{"type":"integer"}}},"pacchetto.OrderItem":{"type":"object","properties":{"border":{"type":"string"},"prepared":{"description":"Pizzas maestro already baked for this item","type":"integer"},"quantity":{"type":"integer"},"size":{"type":"string"},"toppings":{"type":"array","items":{"type":"string"}},"total":{"type":"integer"},"unit_price":{"type":"integer"}}}},"securityDefinitions":{"Bearer":{"description":"Type \"Bearer\" followed by a space and JWT token.","type":"apiKey","name":"Authorization","in":"header"}}}`
 
 // SwaggerInfo holds exported Swagger Info so clients can modify it
 var SwaggerInfo = &swag.Spec{
@@ -2,26 +2,118 @@ package main
 
 import (
 	"time"
+
+	"github.com/taldoflemis/box-box/pacchetto"
+)
+
+const (
+	OrderStatusWaitingPayment     = "waiting_payment"
+	OrderStatusPaymentFailed      = "payment_failed"
+	OrderStatusWaitingToCook      = "waiting_to_cook"
+	OrderStatusWaitingDelivery    = "waiting_delivery"
+	OrderStatusOutForDelivery     = "out_for_delivery"
+	OrderStatusDelivered          = "delivered"
+	OrderStatusCancelled          = "cancelled"
+	OrderStatusCancelledAfterPrep = "cancelled_after_prep"
+	OrderStatusRejected           = "rejected"
+	OrderStatusDeadLetter         = "dead_letter"
+)
+
+const (
+	BorderNone        = "none"
+	BorderCreamCheese = "cream_cheese"
+	BorderCheddar     = "cheddar"
+	BorderChocolate   = "chocolate"
 )
 
+// IsTerminalOrderStatus reports whether an order in status will not change anymore.
+// A cancelled order may still get a cancelled_after_prep event from maestro,
+// but its status stays cancelled in the status bucket.
+// A dead-lettered order only changes again if an operator redrives it with
+// boxbox-dlq, which starts it over from waiting_to_cook; clients reopen the
+// stream to follow it instead of waiting on a redrive that may never come.
+func IsTerminalOrderStatus(status string) bool {
+	switch status {
+	case OrderStatusDelivered, OrderStatusCancelled, OrderStatusCancelledAfterPrep, OrderStatusRejected, OrderStatusPaymentFailed, OrderStatusDeadLetter:
+		return true
+	default:
+		return false
+	}
+}
+
+type NewPizzaOrderItem struct {
+	Size     string   `json:"size" validate:"required,oneof=small medium large"`
+	Border   string   `json:"border,omitempty" validate:"omitempty,oneof=none cream_cheese cheddar chocolate"` // Defaults to none
+	Toppings []string `json:"toppings" validate:"required,min=1,dive,required"`
+	Quantity int      `json:"quantity,omitempty" validate:"omitempty,min=1,max=20"` // Defaults to 1
+}
+
+// NewPizzaOrderRequest orders either the pizzas in Items or the single pizza
+// described by Size, Border and Toppings.
 type NewPizzaOrderRequest struct {
-	Size        string   `json:"size" validate:"required,oneof=small medium large"`
-	Toppings    []string `json:"toppings" validate:"dive,required"`
-	Destination string   `json:"destination" validate:"required"`
-	Username    string   `json:"username" validate:"required"`
+	Size        string              `json:"size,omitempty" validate:"required_without=Items,excluded_with=Items,omitempty,oneof=small medium large"`
+	Border      string              `json:"border,omitempty" validate:"excluded_with=Items,omitempty,oneof=none cream_cheese cheddar chocolate"` // Defaults to none
+	Toppings    []string            `json:"toppings,omitempty" validate:"required_without=Items,excluded_with=Items,omitempty,min=1,dive,required"`
+	Items       []NewPizzaOrderItem `json:"items,omitempty" validate:"required_without=Size,omitempty,min=1,max=20,dive"`
+	Destination string              `json:"destination" validate:"required"`
+	Username    string              `json:"username" validate:"required"`
+	PromoCode   string              `json:"promo_code,omitempty" validate:"omitempty,max=64"`
+}
+
+// OrderItems returns the line items of the order, with defaults filled in.
+func (r NewPizzaOrderRequest) OrderItems() []OrderItem {
+	if len(r.Items) == 0 {
+		return []OrderItem{newOrderItem(NewPizzaOrderItem{Size: r.Size, Border: r.Border, Toppings: r.Toppings})}
+	}
+
+	items := make([]OrderItem, 0, len(r.Items))
+	for _, item := range r.Items {
+		items = append(items, newOrderItem(item))
+	}
+	return items
+}
+
+func newOrderItem(item NewPizzaOrderItem) OrderItem {
+	if item.Border == "" {
+		item.Border = BorderNone
+	}
+	if item.Quantity == 0 {
+		item.Quantity = 1
+	}
+
+	return OrderItem{
+		Size:     item.Size,
+		Border:   item.Border,
+		Toppings: item.Toppings,
+		Quantity: item.Quantity,
+	}
 }
 
 type NewPizzaOrderResponse struct {
 	OrderID   string    `json:"order_id"`
 	OrderedAt time.Time `json:"ordered_at"`
+	Discount  int64     `json:"discount,omitempty"`
+	Total     int64     `json:"total,omitempty"`
+	Currency  string    `json:"currency,omitempty"`
 }
 
-type Order struct {
-	Size        string    `json:"size"`
-	Toppings    []string  `json:"toppings"`
-	Destination string    `json:"destination"`
-	Username    string    `json:"username"`
-	OrderedAt   time.Time `json:"ordered_at"`
-	OrderID     string    `json:"order_id"`
-	Status      string    `json:"status"` // e.g., "pending", "in_progress", "completed"
+type QuoteRequest struct {
+	Items     []NewPizzaOrderItem `json:"items" validate:"required,min=1,max=20,dive"`
+	PromoCode string              `json:"promo_code,omitempty" validate:"omitempty,max=64"`
+	// Username checks the promo code per user limit, the token subject is used with authentication
+	Username string `json:"username,omitempty"`
 }
+
+type QuoteResponse struct {
+	Items     []OrderItem `json:"items"`
+	Subtotal  int64       `json:"subtotal"`
+	PromoCode string      `json:"promo_code,omitempty"`
+	Discount  int64       `json:"discount,omitempty"`
+	Total     int64       `json:"total"`
+	Currency  string      `json:"currency"` // Prices are in minor units of the currency
+}
+
+type (
+	Order     = pacchetto.Order
+	OrderItem = pacchetto.OrderItem
+)
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsTerminalOrderStatus(t *testing.T) {
+	tests := []struct {
+		status string
+		want   bool
+	}{
+		{status: OrderStatusWaitingPayment, want: false},
+		{status: OrderStatusWaitingToCook, want: false},
+		{status: OrderStatusWaitingDelivery, want: false},
+		{status: OrderStatusOutForDelivery, want: false},
+		{status: OrderStatusDelivered, want: true},
+		{status: OrderStatusCancelled, want: true},
+		{status: OrderStatusCancelledAfterPrep, want: true},
+		{status: OrderStatusRejected, want: true},
+		{status: OrderStatusPaymentFailed, want: true},
+		{status: OrderStatusDeadLetter, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			// Act
+			got := IsTerminalOrderStatus(tt.status)
+
+			// Assert
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
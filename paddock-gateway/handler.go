@@ -2,9 +2,14 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -20,24 +25,97 @@ import (
 
 var tracer = otel.Tracer("paddock-gateway")
 
+const (
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+)
+
 type OrderPubSubber interface {
 	PubOrder(ctx context.Context, order Order) error
-	SubLiveOrders(ctx context.Context, flusher http.Flusher) (<-chan Order, error)
+	PubCancellation(ctx context.Context, order Order) error
+	SubLiveOrders(ctx context.Context, flusher http.Flusher, opts SubscribeOptions) (<-chan OrderEvent, error)
+	// SubOrderUpdates streams the status transitions of a single order.
+	SubOrderUpdates(ctx context.Context, orderID string, flusher http.Flusher, opts SubscribeOptions) (<-chan OrderEvent, error)
 	UnsubLiveOrders(ctx context.Context, flusher http.Flusher) error
 }
 
+// OrderEvent is an order state together with its position in the event stream.
+type OrderEvent struct {
+	Order Order
+	// Sequence increases with every event and is sent as the SSE id so
+	// clients can resume after it.
+	Sequence uint64
+}
+
+type DeliverPolicy int
+
+const (
+	// DeliverNew only sends events published after subscribing.
+	DeliverNew DeliverPolicy = iota
+	// DeliverAll replays every event still retained.
+	DeliverAll
+	// DeliverByStartSequence replays events starting at StartSequence.
+	DeliverByStartSequence
+	// DeliverByStartTime replays events published at or after StartTime.
+	DeliverByStartTime
+)
+
+// SubscribeOptions selects where a subscription starts in the event stream.
+type SubscribeOptions struct {
+	Deliver       DeliverPolicy
+	StartSequence uint64
+	StartTime     time.Time
+}
+
+var ErrOrderNotFound = errors.New("order not found")
+
+// OrderGetter returns the latest known state of an order.
+type OrderGetter interface {
+	GetOrder(ctx context.Context, orderID string) (Order, error)
+}
+
+// ErrIdempotencyKeyReused is returned by IdempotencyStore.Reserve when the key
+// was already used for a different request.
+var ErrIdempotencyKeyReused = errors.New("idempotency key reused for a different request")
+
+// IdempotencyStore remembers the response given to each Idempotency-Key so
+// retried requests replay it instead of ordering another pizza.
+type IdempotencyStore interface {
+	// Reserve saves resp under key with the fingerprint of the request. When the
+	// key was already used, the original response is returned and replayed is
+	// true, or ErrIdempotencyKeyReused when the fingerprints differ.
+	Reserve(ctx context.Context, key, fingerprint string, resp NewPizzaOrderResponse) (original NewPizzaOrderResponse, replayed bool, err error)
+	Release(ctx context.Context, key string) error
+}
+
+// OrderOutbox keeps orders that could not be published so they reach the
+// kitchen once the message broker is available again.
+type OrderOutbox interface {
+	Enqueue(ctx context.Context, order Order) error
+}
+
+// GoChannelOrderPubSubber keeps everything in memory. It keeps no event
+// history, so subscriptions only receive new events whatever their options.
 type GoChannelOrderPubSubber struct {
-	liveEventSubscribers map[http.Flusher]chan Order
+	liveEventSubscribers map[http.Flusher]chan OrderEvent
+	orderFilters         map[http.Flusher]string
+	orders               map[string]OrderEvent
+	sequence             uint64
 	mu                   sync.Mutex
 }
 
 func NewGoChannelOrderPubSubber() *GoChannelOrderPubSubber {
 	return &GoChannelOrderPubSubber{
-		liveEventSubscribers: make(map[http.Flusher]chan Order),
+		liveEventSubscribers: make(map[http.Flusher]chan OrderEvent),
+		orderFilters:         make(map[http.Flusher]string),
+		orders:               make(map[string]OrderEvent),
 	}
 }
 
-var _ OrderPubSubber = (*GoChannelOrderPubSubber)(nil)
+var (
+	_ OrderPubSubber = (*GoChannelOrderPubSubber)(nil)
+	_ OrderGetter    = (*GoChannelOrderPubSubber)(nil)
+)
 
 // PubOrder implements OrderPubSubber.
 func (g *GoChannelOrderPubSubber) PubOrder(ctx context.Context, order Order) error {
@@ -49,23 +127,79 @@ func (g *GoChannelOrderPubSubber) PubOrder(ctx context.Context, order Order) err
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
-	for _, subChan := range g.liveEventSubscribers {
-		subChan <- order
+	g.sequence++
+	event := OrderEvent{Order: order, Sequence: g.sequence}
+	g.orders[order.OrderID] = event
+
+	for flusher, subChan := range g.liveEventSubscribers {
+		orderID, filtered := g.orderFilters[flusher]
+		if filtered && orderID != order.OrderID {
+			continue
+		}
+		subChan <- event
 	}
 
 	return nil
 }
 
+// PubCancellation implements OrderPubSubber.
+func (g *GoChannelOrderPubSubber) PubCancellation(ctx context.Context, order Order) error {
+	ctx, span := tracer.Start(ctx, "GoChannelOrderPubSubber.PubCancellation")
+	defer span.End()
+
+	slog.InfoContext(ctx, "publishing order cancellation", slog.String("order_id", order.OrderID))
+
+	order.Status = OrderStatusCancelled
+	if order.Timestamps == nil {
+		order.Timestamps = make(map[string]time.Time)
+	}
+	order.Timestamps[OrderStatusCancelled] = time.Now()
+
+	return g.PubOrder(ctx, order)
+}
+
+// GetOrder implements OrderGetter.
+func (g *GoChannelOrderPubSubber) GetOrder(ctx context.Context, orderID string) (Order, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	event, ok := g.orders[orderID]
+	if !ok {
+		return Order{}, ErrOrderNotFound
+	}
+
+	return event.Order, nil
+}
+
 // SubLiveOrders implements OrderPubSubber for SSE.
-func (g *GoChannelOrderPubSubber) SubLiveOrders(ctx context.Context, flusher http.Flusher) (<-chan Order, error) {
+func (g *GoChannelOrderPubSubber) SubLiveOrders(ctx context.Context, flusher http.Flusher, _ SubscribeOptions) (<-chan OrderEvent, error) {
 	ctx, span := tracer.Start(ctx, "GoChannelOrderPubSubber.SubLiveOrders")
 	defer span.End()
 
 	slog.InfoContext(ctx, "subscribing to live orders (SSE)")
 
-	ch := make(chan Order)
+	ch := make(chan OrderEvent)
+	g.mu.Lock()
+	g.liveEventSubscribers[flusher] = ch
+	g.mu.Unlock()
+	return ch, nil
+}
+
+// SubOrderUpdates implements OrderPubSubber for SSE.
+func (g *GoChannelOrderPubSubber) SubOrderUpdates(ctx context.Context, orderID string, flusher http.Flusher, opts SubscribeOptions) (<-chan OrderEvent, error) {
+	ctx, span := tracer.Start(ctx, "GoChannelOrderPubSubber.SubOrderUpdates")
+	defer span.End()
+
+	slog.InfoContext(ctx, "subscribing to order updates (SSE)", slog.String("order_id", orderID))
+
+	// Buffered so the current state can be sent before the first publish
+	ch := make(chan OrderEvent, 1)
 	g.mu.Lock()
+	if event, ok := g.orders[orderID]; ok && opts.Deliver != DeliverNew && event.Sequence >= opts.StartSequence {
+		ch <- event
+	}
 	g.liveEventSubscribers[flusher] = ch
+	g.orderFilters[flusher] = orderID
 	g.mu.Unlock()
 	return ch, nil
 }
@@ -79,18 +213,52 @@ func (g *GoChannelOrderPubSubber) UnsubLiveOrders(ctx context.Context, flusher h
 
 	g.mu.Lock()
 	delete(g.liveEventSubscribers, flusher)
+	delete(g.orderFilters, flusher)
 	g.mu.Unlock()
 	return nil
 }
 
 type MainHandler struct {
-	orderPubSubber OrderPubSubber
-	health         *healthgo.Health
+	orderPubSubber   OrderPubSubber
+	orderGetter      OrderGetter
+	idempotencyStore IdempotencyStore
+	outbox           OrderOutbox
+	menu             *MenuCatalog
+	pricer           *Pricer
+	promotions       *Promotions
+	retryAfter       string
+	health           *healthgo.Health
+
+	// SSE streams
+	heartbeatInterval time.Duration
+	reconnectDelay    time.Duration
+	drainMu           sync.Mutex
+	draining          chan struct{}
+	streams           sync.WaitGroup
 }
 
-func NewMainHandler(e *echo.Echo, settings *Settings, orderPubSubber OrderPubSubber, health *healthgo.Health) *MainHandler {
+// MainHandlerDeps are what the handler is built on. OrderPubSubber and
+// OrderGetter are required; every other dependency is optional and its feature
+// is off while nil.
+type MainHandlerDeps struct {
+	OrderPubSubber   OrderPubSubber
+	OrderGetter      OrderGetter
+	IdempotencyStore IdempotencyStore
+	Outbox           OrderOutbox
+	Menu             *MenuCatalog
+	Pricer           *Pricer
+	Promotions       *Promotions
+	Auth             echo.MiddlewareFunc
+	RateLimitStore   RateLimitStore
+	Health           *healthgo.Health
+}
+
+func NewMainHandler(e *echo.Echo, settings *Settings, deps MainHandlerDeps) *MainHandler {
 	logger := slog.Default()
 	e.HideBanner = true
+	e.Validator = newRequestValidator()
+	e.HTTPErrorHandler = problemHTTPErrorHandler
+	e.Pre(redactAccessToken)
 	e.Use(slogecho.New(logger))
 	e.Use(middleware.Recover())
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
@@ -114,15 +282,46 @@ func NewMainHandler(e *echo.Echo, settings *Settings, orderPubSubber OrderPubSub
 	))
 
 	handler := &MainHandler{
-		orderPubSubber: orderPubSubber,
-		health:         health,
+		orderPubSubber:   deps.OrderPubSubber,
+		orderGetter:      deps.OrderGetter,
+		idempotencyStore: deps.IdempotencyStore,
+		outbox:           deps.Outbox,
+		menu:             deps.Menu,
+		pricer:           deps.Pricer,
+		promotions:       deps.Promotions,
+		retryAfter:       strconv.Itoa(settings.Orders.RetryAfterInSeconds),
+		health:           deps.Health,
+
+		heartbeatInterval: time.Duration(settings.SSE.HeartbeatIntervalInSeconds) * time.Second,
+		reconnectDelay:    time.Duration(settings.SSE.ReconnectDelayInMilliseconds) * time.Millisecond,
+		draining:          make(chan struct{}),
 	}
 
 	e.GET("/healthz", handler.HealthCheck)
 	v1 := e.Group("/v1")
+	if deps.Auth != nil {
+		v1.Use(deps.Auth)
+	}
 
-	v1.POST("/order", handler.OrderNewPizza)
+	// Only the routes that change orders are rate limited, streams are long lived
+	var limited []echo.MiddlewareFunc
+	if deps.RateLimitStore != nil {
+		limited = append(limited, newRateLimitMiddleware(deps.RateLimitStore, settings.RateLimit))
+	}
+
+	if deps.Menu != nil {
+		v1.GET("/menu", handler.GetMenu)
+	}
+	v1.POST("/order", handler.OrderNewPizza, limited...)
+	if deps.Pricer != nil {
+		v1.POST("/order/quote", handler.QuoteOrder)
+		v1.GET("/order/:id/receipt", handler.GetOrderReceipt)
+	}
 	v1.GET("/order/sse", handler.GetLiveOrdersSSE)
+	v1.GET("/order/:id", handler.GetOrder)
+	v1.GET("/order/:id/sse", handler.GetOrderSSE)
+	v1.GET("/ws", handler.KitchenWebSocket)
+	v1.DELETE("/order/:id", handler.CancelOrder, limited...)
 
 	return handler
 }
@@ -131,11 +330,27 @@ func NewMainHandler(e *echo.Echo, settings *Settings, orderPubSubber OrderPubSub
 //
 // @Summary Create a new pizza order
 // @Tags order
+// @Security Bearer
+// @Description Order several pizzas at once with items, or a single pizza with size, border and toppings.
+// @Description Send an Idempotency-Key header to safely retry: a repeated key replays the original response.
+// @Description When the order cannot be published it is kept in the outbox and answered with 202, or,
+// @Description without an outbox, answered with 503 and a Retry-After header.
+// @Description With authentication enabled the username is the token subject, a different username is rejected with 403.
+// @Description Sizes, borders and toppings must be on the menu and available, otherwise the order is rejected with 422.
+// @Description Orders are rate limited per user and per client IP, see the RateLimit headers; over the limit the answer is 429.
+// @Description A promo code takes its discount off the total; an unknown, expired or used up code is rejected with 422.
 // @Accept json
 // @Produce json
+// @Param Idempotency-Key header string false "Client generated key that identifies this order attempt"
 // @Param order body NewPizzaOrderRequest true "New Pizza Order Request"
 // @Success 200 {object} NewPizzaOrderResponse
-// @Failure 422 {string} string "error"
+// @Success 202 {object} NewPizzaOrderResponse
+// @Failure 400 {object} ProblemDetails
+// @Failure 401 {object} ProblemDetails
+// @Failure 403 {object} ProblemDetails
+// @Failure 422 {object} ProblemDetails
+// @Failure 429 {object} ProblemDetails
+// @Failure 503 {object} ProblemDetails
 // @Router /v1/order [post]
 func (h *MainHandler) OrderNewPizza(c echo.Context) error {
 	ctx := c.Request().Context()
@@ -144,12 +359,42 @@ func (h *MainHandler) OrderNewPizza(c echo.Context) error {
 	err := c.Bind(&req)
 	if err != nil {
 		slog.ErrorContext(ctx, "failed to bind request", slog.String("error", err.Error()))
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+		return writeProblem(c, newProblem(c, http.StatusBadRequest, "The request body is not a valid order"))
+	}
+
+	// Authenticated customers always order for themselves
+	userID, authenticated := authenticatedUser(c)
+	if authenticated {
+		if req.Username != "" && req.Username != userID {
+			slog.InfoContext(ctx, "rejected order for another user", slog.String("username", req.Username))
+			return writeProblem(c, newProblem(c, http.StatusForbidden, "The username does not match the token subject"))
+		}
+		req.Username = userID
+	}
+
+	err = c.Validate(&req)
+	if err != nil {
+		problem, ok := validationProblem(c, err)
+		if !ok {
+			return err
+		}
+		slog.InfoContext(ctx, "rejected invalid order request", slog.Any("errors", problem.Errors))
+		return writeProblem(c, problem)
 	}
 
+	if h.menu != nil {
+		unavailable := h.menu.Menu().Check(req)
+		if len(unavailable) > 0 {
+			problem := newProblem(c, http.StatusUnprocessableEntity, "The order has items that are not on the menu or sold out")
+			problem.Errors = unavailable
+			slog.InfoContext(ctx, "rejected order for unavailable items", slog.Any("errors", problem.Errors))
+			return writeProblem(c, problem)
+		}
+	}
+
+	idempotencyKey := c.Request().Header.Get(HeaderIdempotencyKey)
 	newOrder := Order{
-		Size:        req.Size,
-		Toppings:    req.Toppings,
+		Items:       req.OrderItems(),
 		Destination: req.Destination,
 		Username:    req.Username,
 		OrderedAt:   time.Now(),
@@ -157,23 +402,388 @@ func (h *MainHandler) OrderNewPizza(c echo.Context) error {
 		Status:      "pending",
 	}
 
+	if h.pricer != nil {
+		newOrder.Total, err = h.pricer.Price(newOrder.Items)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to price order", slog.String("error", err.Error()))
+			return err
+		}
+		newOrder.Subtotal = newOrder.Total
+		newOrder.Currency = h.pricer.Currency()
+	}
+
+	var promo PromoCode
+	if req.PromoCode != "" {
+		promo, newOrder.Discount, err = h.checkPromo(ctx, req.PromoCode, newOrder.Username, newOrder.Subtotal, newOrder.OrderedAt)
+		if err != nil {
+			return h.promoProblem(c, err)
+		}
+		newOrder.PromoCode = promo.Code
+		newOrder.Total -= newOrder.Discount
+	}
+
 	resp := NewPizzaOrderResponse{
 		OrderID:   newOrder.OrderID,
 		OrderedAt: newOrder.OrderedAt,
+		Discount:  newOrder.Discount,
+		Total:     newOrder.Total,
+		Currency:  newOrder.Currency,
+	}
+
+	if idempotencyKey != "" && h.idempotencyStore != nil {
+		original, replayed, err := h.idempotencyStore.Reserve(ctx, idempotencyKey, requestFingerprint(newOrder), resp)
+		if errors.Is(err, ErrIdempotencyKeyReused) {
+			slog.InfoContext(ctx, "rejected reused idempotency key")
+			return writeProblem(c, newProblem(c, http.StatusUnprocessableEntity, "The Idempotency-Key was already used for a different order"))
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to reserve idempotency key", slog.String("error", err.Error()))
+			return h.unavailable(c, "The order could not be placed, try again later")
+		}
+
+		if replayed {
+			slog.InfoContext(ctx, "replaying order for repeated idempotency key", slog.String("order_id", original.OrderID))
+			c.Response().Header().Set(HeaderIdempotentReplayed, "true")
+			return c.JSON(http.StatusOK, original)
+		}
+	}
+
+	// Redeemed after the idempotency key so a retried order counts once
+	if newOrder.PromoCode != "" {
+		err = h.promotions.Redeem(ctx, promo, newOrder.Username)
+		if err != nil {
+			h.releaseIdempotencyKey(ctx, idempotencyKey)
+			return h.promoProblem(c, err)
+		}
+	}
+
+	err = h.orderPubSubber.PubOrder(ctx, newOrder)
+	if err == nil {
+		return c.JSON(http.StatusOK, resp)
+	}
+
+	slog.ErrorContext(ctx, "failed to publish order", slog.String("order_id", newOrder.OrderID), slog.String("error", err.Error()))
+
+	if h.outbox != nil {
+		err = h.outbox.Enqueue(ctx, newOrder)
+		if err == nil {
+			return c.JSON(http.StatusAccepted, resp)
+		}
+		slog.ErrorContext(ctx, "failed to keep order in outbox", slog.String("order_id", newOrder.OrderID), slog.String("error", err.Error()))
+	}
+
+	// The order was lost, free the key and the promo code so the client can retry with them
+	h.releaseIdempotencyKey(ctx, idempotencyKey)
+	if newOrder.PromoCode != "" {
+		err = h.promotions.Release(ctx, promo, newOrder.Username)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to release promo code", slog.String("code", promo.Code), slog.String("error", err.Error()))
+		}
+	}
+
+	return h.unavailable(c, "The order could not be placed, try again later")
+}
+
+// requestFingerprint identifies what was ordered, whatever the shape of the
+// request, to tell a retry from another order reusing its Idempotency-Key.
+func requestFingerprint(order Order) string {
+	data, _ := json.Marshal(struct {
+		Items       []OrderItem `json:"items"`
+		Destination string      `json:"destination"`
+		Username    string      `json:"username"`
+		PromoCode   string      `json:"promo_code"`
+	}{order.Items, order.Destination, order.Username, order.PromoCode})
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (h *MainHandler) releaseIdempotencyKey(ctx context.Context, idempotencyKey string) {
+	if idempotencyKey == "" || h.idempotencyStore == nil {
+		return
+	}
+
+	err := h.idempotencyStore.Release(ctx, idempotencyKey)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to release idempotency key", slog.String("error", err.Error()))
+	}
+}
+
+// checkPromo returns the promo code and its discount on subtotal. Without
+// promotions every code is unknown.
+func (h *MainHandler) checkPromo(ctx context.Context, code string, username string, subtotal int64, now time.Time) (PromoCode, int64, error) {
+	if h.promotions == nil || h.pricer == nil {
+		return PromoCode{}, 0, ErrPromoCodeNotFound
+	}
+	return h.promotions.Check(ctx, code, username, subtotal, now)
+}
+
+// promoProblem answers 422 for promo codes the customer cannot use and 503
+// when redemptions cannot be counted.
+func (h *MainHandler) promoProblem(c echo.Context, err error) error {
+	ctx := c.Request().Context()
+
+	fe, ok := promoFieldError(err)
+	if !ok {
+		slog.ErrorContext(ctx, "failed to apply promo code", slog.String("error", err.Error()))
+		return h.unavailable(c, "The promo code could not be applied, try again later")
+	}
+
+	slog.InfoContext(ctx, "rejected promo code", slog.String("rule", fe.Rule))
+	problem := newProblem(c, http.StatusUnprocessableEntity, "The promo code cannot be applied")
+	problem.Errors = []FieldError{fe}
+	return writeProblem(c, problem)
+}
+
+// unavailable tells the client why the request failed and when to try again.
+func (h *MainHandler) unavailable(c echo.Context, detail string) error {
+	c.Response().Header().Set(echo.HeaderRetryAfter, h.retryAfter)
+	return writeProblem(c, newProblem(c, http.StatusServiceUnavailable, detail))
+}
+
+// GetMenu godoc
+//
+// @Summary Get the sizes, borders and toppings that can be ordered
+// @Description Items with available false are sold out and rejected with 422 when ordered.
+// @Tags menu
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} Menu
+// @Failure 401 {object} ProblemDetails
+// @Router /v1/menu [get]
+func (h *MainHandler) GetMenu(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.menu.Menu())
+}
+
+// QuoteOrder godoc
+//
+// @Summary Price pizzas without ordering them
+// @Description Prices are in minor units of the currency, cents for EUR.
+// @Description A promo code is checked and its discount applied, but it is only redeemed by placing the order.
+// @Tags order
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param quote body QuoteRequest true "Pizzas to price"
+// @Success 200 {object} QuoteResponse
+// @Failure 400 {object} ProblemDetails
+// @Failure 401 {object} ProblemDetails
+// @Failure 422 {object} ProblemDetails
+// @Failure 503 {object} ProblemDetails
+// @Router /v1/order/quote [post]
+func (h *MainHandler) QuoteOrder(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req QuoteRequest
+	err := c.Bind(&req)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to bind request", slog.String("error", err.Error()))
+		return writeProblem(c, newProblem(c, http.StatusBadRequest, "The request body is not a valid quote request"))
 	}
 
-	h.orderPubSubber.PubOrder(c.Request().Context(), newOrder)
+	err = c.Validate(&req)
+	if err != nil {
+		problem, ok := validationProblem(c, err)
+		if !ok {
+			return err
+		}
+		return writeProblem(c, problem)
+	}
+
+	order := NewPizzaOrderRequest{Items: req.Items}
+	if h.menu != nil {
+		unavailable := h.menu.Menu().Check(order)
+		if len(unavailable) > 0 {
+			problem := newProblem(c, http.StatusUnprocessableEntity, "The quote has items that are not on the menu or sold out")
+			problem.Errors = unavailable
+			return writeProblem(c, problem)
+		}
+	}
+
+	items := order.OrderItems()
+	subtotal, err := h.pricer.Price(items)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to price quote", slog.String("error", err.Error()))
+		return err
+	}
+
+	resp := QuoteResponse{
+		Items:    items,
+		Subtotal: subtotal,
+		Total:    subtotal,
+		Currency: h.pricer.Currency(),
+	}
+
+	if req.PromoCode != "" {
+		if userID, authenticated := authenticatedUser(c); authenticated {
+			req.Username = userID
+		}
+
+		promo, discount, err := h.checkPromo(ctx, req.PromoCode, req.Username, subtotal, time.Now())
+		if err != nil {
+			return h.promoProblem(c, err)
+		}
+		resp.PromoCode = promo.Code
+		resp.Discount = discount
+		resp.Total -= discount
+	}
 
 	return c.JSON(http.StatusOK, resp)
 }
 
+// GetOrderReceipt godoc
+//
+// @Summary Get the itemised receipt of an order
+// @Description Prices are the ones the order was placed with, in minor units of the currency.
+// @Description Send Accept: text/plain for a printable receipt.
+// @Tags order
+// @Security Bearer
+// @Produce json
+// @Produce plain
+// @Param id path string true "Order ID"
+// @Success 200 {object} Receipt
+// @Failure 401 {object} ProblemDetails
+// @Failure 403 {object} ProblemDetails
+// @Failure 404 {object} ProblemDetails
+// @Router /v1/order/{id}/receipt [get]
+func (h *MainHandler) GetOrderReceipt(c echo.Context) error {
+	ctx := c.Request().Context()
+	orderID := c.Param("id")
+
+	order, err := h.orderGetter.GetOrder(ctx, orderID)
+	if errors.Is(err, ErrOrderNotFound) {
+		return writeProblem(c, newProblem(c, http.StatusNotFound, "Order not found"))
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to get order", slog.String("order_id", orderID), slog.String("error", err.Error()))
+		return err
+	}
+
+	if !ownsOrder(c, order) {
+		return writeProblem(c, newProblem(c, http.StatusForbidden, "Only the customer who placed the order can see its receipt"))
+	}
+
+	// Orders placed before pricing existed have nothing to show
+	if order.Currency == "" {
+		return writeProblem(c, newProblem(c, http.StatusNotFound, "Order has no receipt"))
+	}
+
+	receipt := NewReceipt(order)
+	if !strings.Contains(c.Request().Header.Get(echo.HeaderAccept), echo.MIMETextPlain) {
+		return c.JSON(http.StatusOK, receipt)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, echo.MIMETextPlainCharsetUTF8)
+	c.Response().WriteHeader(http.StatusOK)
+	return receipt.WriteText(c.Response())
+}
+
+// GetOrder godoc
+//
+// @Summary Get the current status of an order
+// @Tags order
+// @Security Bearer
+// @Produce json
+// @Param id path string true "Order ID"
+// @Success 200 {object} Order
+// @Failure 401 {object} ProblemDetails
+// @Failure 404 {object} ProblemDetails
+// @Router /v1/order/{id} [get]
+func (h *MainHandler) GetOrder(c echo.Context) error {
+	ctx := c.Request().Context()
+	orderID := c.Param("id")
+
+	order, err := h.orderGetter.GetOrder(ctx, orderID)
+	if errors.Is(err, ErrOrderNotFound) {
+		return writeProblem(c, newProblem(c, http.StatusNotFound, "Order not found"))
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to get order", slog.String("order_id", orderID), slog.String("error", err.Error()))
+		return err
+	}
+
+	// Other customers' orders are not found rather than forbidden, their IDs
+	// are not confirmed either
+	if !ownsOrder(c, order) {
+		return writeProblem(c, newProblem(c, http.StatusNotFound, "Order not found"))
+	}
+
+	return c.JSON(http.StatusOK, order)
+}
+
+// ownsOrder reports whether the request may see the order: anyone without
+// authentication, only the customer who placed it with authentication.
+func ownsOrder(c echo.Context, order Order) bool {
+	userID, authenticated := authenticatedUser(c)
+	return !authenticated || order.Username == userID
+}
+
+// CancelOrder godoc
+//
+// @Summary Cancel an order that was not sent to delivery yet
+// @Description The cancellation is asynchronous: orders still waiting for payment are dropped by caixa and
+// @Description orders still waiting to cook by maestro, while orders whose dough is already being made get a cancelled_after_prep event.
+// @Description The cancelled status is final, later events never replace it.
+// @Tags order
+// @Security Bearer
+// @Produce json
+// @Param id path string true "Order ID"
+// @Success 202 {object} Order
+// @Failure 401 {object} ProblemDetails
+// @Failure 403 {object} ProblemDetails
+// @Failure 404 {object} ProblemDetails
+// @Failure 409 {object} ProblemDetails
+// @Failure 429 {object} ProblemDetails
+// @Router /v1/order/{id} [delete]
+func (h *MainHandler) CancelOrder(c echo.Context) error {
+	ctx := c.Request().Context()
+	orderID := c.Param("id")
+
+	order, err := h.orderGetter.GetOrder(ctx, orderID)
+	if errors.Is(err, ErrOrderNotFound) {
+		return writeProblem(c, newProblem(c, http.StatusNotFound, "Order not found"))
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to get order", slog.String("order_id", orderID), slog.String("error", err.Error()))
+		return err
+	}
+
+	if !ownsOrder(c, order) {
+		slog.InfoContext(ctx, "rejected cancellation of another user's order", slog.String("order_id", orderID))
+		return writeProblem(c, newProblem(c, http.StatusForbidden, "Only the customer who placed the order can cancel it"))
+	}
+
+	if order.Status != OrderStatusWaitingPayment && order.Status != OrderStatusWaitingToCook {
+		slog.InfoContext(ctx, "order can no longer be cancelled", slog.String("order_id", orderID), slog.String("status", order.Status))
+		return writeProblem(c, newProblem(c, http.StatusConflict, "Order can no longer be cancelled, status is "+order.Status))
+	}
+
+	err = h.orderPubSubber.PubCancellation(ctx, order)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to publish order cancellation", slog.String("order_id", orderID), slog.String("error", err.Error()))
+		return err
+	}
+
+	order.Status = OrderStatusCancelled
+
+	return c.JSON(http.StatusAccepted, order)
+}
+
 // GetLiveOrdersSSE godoc
 //
 // @Summary Get live orders via Server-Sent Events (SSE)
+// @Description Every event id is the stream sequence of the order update. Reconnecting with a
+// @Description Last-Event-ID header resumes right after that event.
 // @Tags order
+// @Security Bearer
 // @Produce  text/event-stream
+// @Param Last-Event-ID header string false "Resume after this event id"
+// @Param deliver query string false "Where the stream starts" Enums(new, all, since) default(new)
+// @Param since query string false "RFC 3339 timestamp to replay from, implies deliver=since"
 // @Success 200 {object} Order
-// @Router /v1/orders/sse [get]
+// @Failure 400 {object} ProblemDetails
+// @Failure 401 {object} ProblemDetails
+// @Router /v1/order/sse [get]
 func (h *MainHandler) GetLiveOrdersSSE(c echo.Context) error {
 	ctx := c.Request().Context()
 	flusher, ok := c.Response().Writer.(http.Flusher)
@@ -182,37 +792,84 @@ func (h *MainHandler) GetLiveOrdersSSE(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Streaming unsupported")
 	}
 
-	ch, err := h.orderPubSubber.SubLiveOrders(ctx, flusher)
+	opts, err := parseSubscribeOptions(c, DeliverNew)
+	if err != nil {
+		return writeProblem(c, newProblem(c, http.StatusBadRequest, err.Error()))
+	}
+
+	ch, err := h.orderPubSubber.SubLiveOrders(ctx, flusher, opts)
 	if err != nil {
 		slog.ErrorContext(ctx, "failed to subscribe to live orders", slog.String("error", err.Error()))
 		return err
 	}
+	defer h.orderPubSubber.UnsubLiveOrders(ctx, flusher)
 
-	c.Response().Header().Set(echo.HeaderContentType, "text/event-stream")
-	c.Response().Header().Set("Cache-Control", "no-cache")
-	c.Response().Header().Set("Connection", "keep-alive")
-
-	notify := c.Request().Context().Done()
-	for {
-		select {
-		case <-notify:
-			slog.InfoContext(ctx, "client closed connection")
-			return h.orderPubSubber.UnsubLiveOrders(ctx, flusher)
-		case resp := <-ch:
-			data, err := json.Marshal(resp)
-			if err != nil {
-				slog.ErrorContext(ctx, "marshal order for SSE", slog.String("error", err.Error()))
-				continue
-			}
-			_, err = c.Response().Writer.Write([]byte("data: " + string(data) + "\n\n"))
-			if err != nil {
-				slog.ErrorContext(ctx, "write SSE", slog.String("error", err.Error()))
-				h.orderPubSubber.UnsubLiveOrders(ctx, flusher)
-				return err
-			}
-			flusher.Flush()
-		}
+	return h.streamOrderEvents(c, flusher, ch, func(OrderEvent) bool { return false })
+}
+
+// GetOrderSSE godoc
+//
+// @Summary Track a single order via Server-Sent Events (SSE)
+// @Description Sends one event per status transition of the order, starting from the first one,
+// @Description and closes the stream once the order reaches a terminal status.
+// @Description With deliver=new, an order already in a terminal status gets that status without an id and the stream closes.
+// @Description Reconnecting with a Last-Event-ID header resumes right after that event.
+// @Tags order
+// @Security Bearer
+// @Produce  text/event-stream
+// @Param id path string true "Order ID"
+// @Param Last-Event-ID header string false "Resume after this event id"
+// @Param deliver query string false "Where the stream starts" Enums(new, all, since) default(all)
+// @Param since query string false "RFC 3339 timestamp to replay from, implies deliver=since"
+// @Success 200 {object} Order
+// @Failure 400 {object} ProblemDetails
+// @Failure 401 {object} ProblemDetails
+// @Failure 404 {object} ProblemDetails
+// @Router /v1/order/{id}/sse [get]
+func (h *MainHandler) GetOrderSSE(c echo.Context) error {
+	ctx := c.Request().Context()
+	orderID := c.Param("id")
+
+	flusher, ok := c.Response().Writer.(http.Flusher)
+	if !ok {
+		slog.ErrorContext(ctx, "streaming unsupported by response writer")
+		return echo.NewHTTPError(http.StatusInternalServerError, "Streaming unsupported")
+	}
+
+	opts, err := parseSubscribeOptions(c, DeliverAll)
+	if err != nil {
+		return writeProblem(c, newProblem(c, http.StatusBadRequest, err.Error()))
+	}
+
+	order, err := h.orderGetter.GetOrder(ctx, orderID)
+	if errors.Is(err, ErrOrderNotFound) {
+		return writeProblem(c, newProblem(c, http.StatusNotFound, "Order not found"))
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to get order", slog.String("order_id", orderID), slog.String("error", err.Error()))
+		return err
+	}
+	if !ownsOrder(c, order) {
+		return writeProblem(c, newProblem(c, http.StatusNotFound, "Order not found"))
 	}
+
+	if opts.Deliver == DeliverNew && IsTerminalOrderStatus(order.Status) {
+		// No new event will ever come, send the final status and close
+		ch := make(chan OrderEvent, 1)
+		ch <- OrderEvent{Order: order}
+		return h.streamOrderEvents(c, flusher, ch, func(OrderEvent) bool { return true })
+	}
+
+	ch, err := h.orderPubSubber.SubOrderUpdates(ctx, orderID, flusher, opts)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to subscribe to order updates", slog.String("order_id", orderID), slog.String("error", err.Error()))
+		return err
+	}
+	defer h.orderPubSubber.UnsubLiveOrders(ctx, flusher)
+
+	return h.streamOrderEvents(c, flusher, ch, func(event OrderEvent) bool {
+		return IsTerminalOrderStatus(event.Order.Status)
+	})
 }
 
 // HealthCheck godoc
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryIdempotencyStore is an IdempotencyStore for tests.
+type memoryIdempotencyStore struct {
+	records map[string]idempotencyRecord
+	mu      sync.Mutex
+}
+
+func (s *memoryIdempotencyStore) Reserve(_ context.Context, key, fingerprint string, resp NewPizzaOrderResponse) (NewPizzaOrderResponse, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	original, ok := s.records[key]
+	if !ok {
+		s.records[key] = idempotencyRecord{NewPizzaOrderResponse: resp, Fingerprint: fingerprint}
+		return resp, false, nil
+	}
+	if original.Fingerprint != fingerprint {
+		return NewPizzaOrderResponse{}, false, ErrIdempotencyKeyReused
+	}
+	return original.NewPizzaOrderResponse, true, nil
+}
+
+func (s *memoryIdempotencyStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
+
+func TestOrderNewPizzaIdempotencyKey(t *testing.T) {
+	// Arrange
+	e := echo.New()
+	pubSubber := NewGoChannelOrderPubSubber()
+	store := &memoryIdempotencyStore{records: make(map[string]idempotencyRecord)}
+	NewMainHandler(e, &Settings{}, MainHandlerDeps{OrderPubSubber: pubSubber, OrderGetter: pubSubber, IdempotencyStore: store})
+
+	order := func(key, destination string) *httptest.ResponseRecorder {
+		body := `{"size":"large","toppings":["pepperoni"],"destination":"` + destination + `","username":"charles_leclerc"}`
+		req := httptest.NewRequest(http.MethodPost, "/v1/order", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderIdempotencyKey, key)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Act
+	first := order("7b9c1f0e", "Garage #16")
+	retried := order("7b9c1f0e", "Garage #16")
+	reused := order("7b9c1f0e", "Garage #55")
+
+	// Assert
+	require.Equal(t, http.StatusOK, first.Code)
+	var placed NewPizzaOrderResponse
+	require.NoError(t, json.Unmarshal(first.Body.Bytes(), &placed))
+	assert.NotEmpty(t, placed.OrderID)
+
+	require.Equal(t, http.StatusOK, retried.Code)
+	assert.Equal(t, "true", retried.Header().Get(HeaderIdempotentReplayed))
+	var replayed NewPizzaOrderResponse
+	require.NoError(t, json.Unmarshal(retried.Body.Bytes(), &replayed))
+	assert.Equal(t, placed.OrderID, replayed.OrderID)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, reused.Code)
+	assert.Empty(t, reused.Header().Get(HeaderIdempotentReplayed))
+}
+
+func TestOrderNewPizzaWithoutIdempotencyStore(t *testing.T) {
+	// Arrange
+	e := echo.New()
+	pubSubber := NewGoChannelOrderPubSubber()
+	NewMainHandler(e, &Settings{}, MainHandlerDeps{OrderPubSubber: pubSubber, OrderGetter: pubSubber})
+
+	order := func() *httptest.ResponseRecorder {
+		body := `{"size":"large","toppings":["pepperoni"],"destination":"Garage #16","username":"charles_leclerc"}`
+		req := httptest.NewRequest(http.MethodPost, "/v1/order", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderIdempotencyKey, "7b9c1f0e")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Act
+	first := order()
+	second := order()
+
+	// Assert
+	require.Equal(t, http.StatusOK, first.Code)
+	require.Equal(t, http.StatusOK, second.Code)
+	assert.Empty(t, second.Header().Get(HeaderIdempotentReplayed), "without a store the key is ignored")
+
+	var firstResp, secondResp NewPizzaOrderResponse
+	require.NoError(t, json.Unmarshal(first.Body.Bytes(), &firstResp))
+	require.NoError(t, json.Unmarshal(second.Body.Bytes(), &secondResp))
+	assert.NotEqual(t, firstResp.OrderID, secondResp.OrderID)
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	_ "net/http/pprof"
 
@@ -73,6 +74,12 @@ func main() {
 	errChan := make(chan error)
 	server := echo.New()
 	server.HideBanner = true
+	server.IPExtractor, err = newIPExtractor(settings.TrustedProxies)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to set up client IP extraction", slog.Any("err", err))
+		retcode = 1
+		return
+	}
 
 	slog.InfoContext(ctx, "Connecting to NATS server")
 	nc, err := settings.Nats.GetNatsClient()
@@ -82,13 +89,111 @@ func main() {
 		return
 	}
 
-	orderPubSubber, err := NewNATSOrderPubSubber(nc, "orders", "ORDERS")
+	broadcaster, err := NewBroadcaster(settings.SSE.BufferSize, SlowConsumerPolicy(settings.SSE.SlowConsumerPolicy))
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to create broadcaster", slog.Any("err", err))
+		retcode = 1
+		return
+	}
+
+	duplicateWindow := time.Duration(settings.Orders.DuplicateWindowInSeconds) * time.Second
+	orderPubSubber, err := NewNATSOrderPubSubber(nc, "orders", "ORDERS", "ORDERS_STATUS", duplicateWindow, broadcaster)
 	if err != nil {
 		slog.ErrorContext(ctx, "failed to create order pub/subber", slog.Any("err", err))
 		retcode = 1
 		return
 	}
 
+	idempotencyKeyTTL := time.Duration(settings.Orders.IdempotencyKeyTTLInSeconds) * time.Second
+	idempotencyStore, err := NewNATSIdempotencyStore(nc, "ORDERS_IDEMPOTENCY", idempotencyKeyTTL)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to create idempotency store", slog.Any("err", err))
+		retcode = 1
+		return
+	}
+
+	var outbox OrderOutbox
+	if settings.Orders.Outbox.Enabled {
+		slog.InfoContext(ctx, "Setting up order outbox", slog.String("directory", settings.Orders.Outbox.Directory))
+		relayInterval := time.Duration(settings.Orders.Outbox.RelayIntervalInSeconds) * time.Second
+		fileOutbox, err := NewFileOrderOutbox(settings.Orders.Outbox.Directory, orderPubSubber, nc.IsConnected, relayInterval)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to create order outbox", slog.Any("err", err))
+			retcode = 1
+			return
+		}
+		go fileOutbox.Relay(ctx)
+		outbox = fileOutbox
+	}
+
+	menu, err := NewMenuCatalog(settings.Menu.Path)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to load menu", slog.Any("err", err))
+		retcode = 1
+		return
+	}
+	err = menu.Watch(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to watch menu", slog.Any("err", err))
+		retcode = 1
+		return
+	}
+
+	var auth echo.MiddlewareFunc
+	if settings.Auth.Enabled {
+		slog.InfoContext(ctx, "Loading JWKS", slog.String("source", settings.Auth.JWKS))
+		jwks, err := NewJWKS(ctx, settings.Auth.JWKS)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to load JWKS", slog.Any("err", err))
+			retcode = 1
+			return
+		}
+		go jwks.Refresh(ctx, time.Duration(settings.Auth.RefreshIntervalInSeconds)*time.Second)
+		auth = NewJWTMiddleware(jwks, settings.Auth)
+	}
+
+	var rateLimitStore RateLimitStore
+	if settings.RateLimit.Enabled {
+		slog.InfoContext(ctx, "Setting up rate limiting", slog.String("store", settings.RateLimit.Store))
+		switch settings.RateLimit.Store {
+		case "nats":
+			// An idle bucket is full again after its period, it can expire then
+			ttl := time.Duration(max(settings.RateLimit.PerUser.PeriodInSeconds, settings.RateLimit.PerIP.PeriodInSeconds)) * time.Second
+			rateLimitStore, err = NewNATSRateLimitStore(nc, settings.RateLimit.Bucket, ttl)
+			if err != nil {
+				slog.ErrorContext(ctx, "failed to create rate limit store", slog.Any("err", err))
+				retcode = 1
+				return
+			}
+		default:
+			rateLimitStore = NewMemoryRateLimitStore()
+		}
+	}
+
+	var promotions *Promotions
+	if settings.Promotions.Enabled {
+		slog.InfoContext(ctx, "Setting up promo codes", slog.String("store", settings.Promotions.Store), slog.Int("codes", len(settings.Promotions.Codes)))
+		var promoStore PromoStore
+		switch settings.Promotions.Store {
+		case "nats":
+			promoStore, err = NewNATSPromoStore(nc, settings.Promotions.CodesBucket, settings.Promotions.RedemptionsBucket, settings.Promotions.Codes)
+			if err != nil {
+				slog.ErrorContext(ctx, "failed to create promo code store", slog.Any("err", err))
+				retcode = 1
+				return
+			}
+		default:
+			promoStore = NewMemoryPromoStore(settings.Promotions.Codes)
+		}
+
+		promotions, err = NewPromotions(promoStore)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to set up promo codes", slog.Any("err", err))
+			retcode = 1
+			return
+		}
+	}
+
 	slog.InfoContext(ctx, "Setting up health checker")
 	health, err := healthgo.New(
 		healthgo.WithComponent(healthgo.Component{
@@ -111,7 +216,18 @@ func main() {
 		return
 	}
 
-	NewMainHandler(server, settings, orderPubSubber, health)
+	handler := NewMainHandler(server, settings, MainHandlerDeps{
+		OrderPubSubber:   orderPubSubber,
+		OrderGetter:      orderPubSubber,
+		IdempotencyStore: idempotencyStore,
+		Outbox:           outbox,
+		Menu:             menu,
+		Pricer:           NewPricer(settings.Pricing),
+		Promotions:       promotions,
+		Auth:             auth,
+		RateLimitStore:   rateLimitStore,
+		Health:           health,
+	})
 	server.GET("/swagger/*", echoSwagger.WrapHandler)
 	pprof.Register(server)
 
@@ -129,8 +245,17 @@ func main() {
 		// Wait for first Signal arrives
 	}
 
-	err = server.Shutdown(ctx)
+	// ctx is already cancelled here, the shutdown gets its own deadline
+	grace := time.Duration(settings.SSE.ShutdownGraceTimeoutInSeconds) * time.Second
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+
+	slog.InfoContext(shutdownCtx, "Draining live order streams")
+	handler.Drain(shutdownCtx)
+	orderPubSubber.Close()
+
+	err = server.Shutdown(shutdownCtx)
 	if err != nil {
-		slog.ErrorContext(ctx, "failed to shutdown gracefully the server", slog.Any("err", err))
+		slog.ErrorContext(shutdownCtx, "failed to shutdown gracefully the server", slog.Any("err", err))
 	}
 }
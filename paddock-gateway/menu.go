@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	_ "embed"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-playground/validator/v10"
+	"github.com/spf13/viper"
+)
+
+//go:embed menu.yaml
+var baseMenu []byte
+
+// MenuItem is a size, border or topping the kitchen offers.
+type MenuItem struct {
+	Name      string   `mapstructure:"name" json:"name" validate:"required"`
+	Available bool     `mapstructure:"available" json:"available"`
+	Allergens []string `mapstructure:"allergens" json:"allergens,omitempty"`
+}
+
+type Menu struct {
+	Sizes    []MenuItem `mapstructure:"sizes" json:"sizes" validate:"required,min=1,dive"`
+	Borders  []MenuItem `mapstructure:"borders" json:"borders" validate:"required,min=1,dive"`
+	Toppings []MenuItem `mapstructure:"toppings" json:"toppings" validate:"required,min=1,dive"`
+}
+
+// Check reports the parts of the order that are not on the menu or sold out.
+func (m *Menu) Check(req NewPizzaOrderRequest) []FieldError {
+	var errs []FieldError
+
+	check := func(items []MenuItem, field, name string) {
+		for _, item := range items {
+			if item.Name != name {
+				continue
+			}
+			if !item.Available {
+				errs = append(errs, FieldError{Field: field, Rule: "available", Message: "is sold out"})
+			}
+			return
+		}
+		errs = append(errs, FieldError{Field: field, Rule: "menu", Message: "is not on the menu"})
+	}
+
+	checkPizza := func(prefix, size, border string, toppings []string) {
+		check(m.Sizes, prefix+"size", size)
+		if border != "" {
+			check(m.Borders, prefix+"border", border)
+		}
+		for i, topping := range toppings {
+			check(m.Toppings, fmt.Sprintf("%stoppings[%d]", prefix, i), topping)
+		}
+	}
+
+	if len(req.Items) == 0 {
+		checkPizza("", req.Size, req.Border, req.Toppings)
+	}
+	for i, item := range req.Items {
+		checkPizza(fmt.Sprintf("items[%d].", i), item.Size, item.Border, item.Toppings)
+	}
+
+	return errs
+}
+
+// MenuCatalog holds the current menu. It is read from the file at path, or
+// from the embedded menu.yaml when path is empty.
+type MenuCatalog struct {
+	path string
+	menu atomic.Pointer[Menu]
+}
+
+func NewMenuCatalog(path string) (*MenuCatalog, error) {
+	catalog := &MenuCatalog{path: path}
+
+	err := catalog.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	return catalog, nil
+}
+
+// Menu returns the menu loaded last.
+func (m *MenuCatalog) Menu() *Menu {
+	return m.menu.Load()
+}
+
+// Load reads the menu again, the previous one is kept when the new one is invalid.
+func (m *MenuCatalog) Load() error {
+	data := baseMenu
+	if m.path != "" {
+		var err error
+		data, err = os.ReadFile(m.path)
+		if err != nil {
+			return fmt.Errorf("failed to read menu from %s: %w", m.path, err)
+		}
+	}
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	err := v.ReadConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to parse menu: %w", err)
+	}
+
+	var menu Menu
+	err = v.Unmarshal(&menu)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal menu: %w", err)
+	}
+
+	err = validator.New().Struct(menu)
+	if err != nil {
+		return fmt.Errorf("invalid menu: %w", err)
+	}
+
+	m.menu.Store(&menu)
+	return nil
+}
+
+// Watch reloads the menu whenever its file changes until ctx is done. The
+// directory is watched rather than the file, so editors and volume mounts that
+// replace the file are picked up too.
+func (m *MenuCatalog) Watch(ctx context.Context) error {
+	if m.path == "" {
+		return nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	err = watcher.Add(filepath.Dir(m.path))
+	if err != nil {
+		watcher.Close()
+		return err
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
+					continue
+				}
+
+				err := m.Load()
+				if err != nil {
+					slog.ErrorContext(ctx, "failed to reload menu, keeping the previous one", slog.String("error", err.Error()))
+					continue
+				}
+				slog.InfoContext(ctx, "reloaded menu", slog.String("path", m.path))
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.ErrorContext(ctx, "menu watcher failed", slog.String("error", err.Error()))
+			}
+		}
+	}()
+
+	return nil
+}
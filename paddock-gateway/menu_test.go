@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMenuCheck(t *testing.T) {
+	// Arrange
+	catalog, err := NewMenuCatalog("")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		req        NewPizzaOrderRequest
+		wantFields []string
+	}{
+		{
+			name: "everything on the menu",
+			req:  NewPizzaOrderRequest{Size: "large", Border: "cheddar", Toppings: []string{"pepperoni", "basil"}},
+		},
+		{
+			name:       "unknown topping",
+			req:        NewPizzaOrderRequest{Size: "large", Toppings: []string{"pepperoni", "catupiry"}},
+			wantFields: []string{"toppings[1]"},
+		},
+		{
+			name:       "sold out topping",
+			req:        NewPizzaOrderRequest{Size: "small", Toppings: []string{"pineapple"}},
+			wantFields: []string{"toppings[0]"},
+		},
+		{
+			name: "line items",
+			req: NewPizzaOrderRequest{Items: []NewPizzaOrderItem{
+				{Size: "large", Toppings: []string{"pepperoni"}},
+				{Size: "small", Border: "catupiry", Toppings: []string{"pineapple"}},
+			}},
+			wantFields: []string{"items[1].border", "items[1].toppings[0]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			errs := catalog.Menu().Check(tt.req)
+
+			// Assert
+			fields := make([]string, 0, len(errs))
+			for _, fe := range errs {
+				fields = append(fields, fe.Field)
+			}
+			assert.ElementsMatch(t, tt.wantFields, fields)
+		})
+	}
+}
+
+func TestMenuCatalogLoad(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "menu.yaml")
+	writeMenu := func(pepperoniAvailable string) {
+		menu := "sizes: [{name: large, available: true}]\n" +
+			"borders: [{name: none, available: true}]\n" +
+			"toppings: [{name: pepperoni, available: " + pepperoniAvailable + "}]\n"
+		require.NoError(t, os.WriteFile(path, []byte(menu), 0o600))
+	}
+	writeMenu("true")
+	catalog, err := NewMenuCatalog(path)
+	require.NoError(t, err)
+	req := NewPizzaOrderRequest{Size: "large", Toppings: []string{"pepperoni"}}
+
+	// Act
+	writeMenu("false")
+	soldOutErr := catalog.Load()
+	soldOut := catalog.Menu().Check(req)
+
+	require.NoError(t, os.WriteFile(path, []byte("toppings: []\n"), 0o600))
+	invalidErr := catalog.Load()
+	afterInvalid := catalog.Menu().Check(req)
+
+	// Assert
+	assert.NoError(t, soldOutErr)
+	assert.Len(t, soldOut, 1)
+	assert.Error(t, invalidErr)
+	assert.Equal(t, soldOut, afterInvalid, "an invalid menu keeps the previous one")
+}
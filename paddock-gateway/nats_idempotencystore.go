@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// NATSIdempotencyStore keeps Idempotency-Key reservations in a JetStream
+// key-value bucket, so every gateway replica sees the same keys.
+type NATSIdempotencyStore struct {
+	kv jetstream.KeyValue
+}
+
+var _ IdempotencyStore = (*NATSIdempotencyStore)(nil)
+
+func NewNATSIdempotencyStore(nc *nats.Conn, bucket string, ttl time.Duration) (*NATSIdempotencyStore, error) {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		slog.Error("failed to create jetstream context", "error", err)
+		return nil, err
+	}
+
+	kv, err := js.CreateOrUpdateKeyValue(context.Background(), jetstream.KeyValueConfig{
+		Bucket:      bucket,
+		Description: "Responses given to POST /v1/order per Idempotency-Key",
+		TTL:         ttl,
+	})
+	if err != nil {
+		slog.Error("failed to create idempotency bucket", "error", err)
+		return nil, err
+	}
+
+	return &NATSIdempotencyStore{kv: kv}, nil
+}
+
+// idempotencyRecord is what is kept per key. The response fields are inlined,
+// records saved before fingerprints existed have none and match any request.
+type idempotencyRecord struct {
+	NewPizzaOrderResponse
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+// Reserve implements IdempotencyStore.
+func (s *NATSIdempotencyStore) Reserve(ctx context.Context, key, fingerprint string, resp NewPizzaOrderResponse) (NewPizzaOrderResponse, bool, error) {
+	ctx, span := tracer.Start(ctx, "NATSIdempotencyStore.Reserve")
+	defer span.End()
+
+	data, err := json.Marshal(idempotencyRecord{NewPizzaOrderResponse: resp, Fingerprint: fingerprint})
+	if err != nil {
+		return NewPizzaOrderResponse{}, false, err
+	}
+
+	// Create only succeeds for the first writer, so two replicas racing on the
+	// same key agree on a single order.
+	_, err = s.kv.Create(ctx, bucketKey(key), data)
+	if err == nil {
+		return resp, false, nil
+	}
+	if !errors.Is(err, jetstream.ErrKeyExists) {
+		slog.ErrorContext(ctx, "failed to reserve idempotency key", "error", err)
+		span.SetStatus(codes.Error, "failed to reserve idempotency key")
+		span.RecordError(err)
+		return NewPizzaOrderResponse{}, false, err
+	}
+
+	entry, err := s.kv.Get(ctx, bucketKey(key))
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to get reserved idempotency key", "error", err)
+		span.SetStatus(codes.Error, "failed to get reserved idempotency key")
+		span.RecordError(err)
+		return NewPizzaOrderResponse{}, false, err
+	}
+
+	var original idempotencyRecord
+	err = json.Unmarshal(entry.Value(), &original)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to unmarshal reserved idempotency key", "error", err)
+		span.SetStatus(codes.Error, "failed to unmarshal reserved idempotency key")
+		span.RecordError(err)
+		return NewPizzaOrderResponse{}, false, err
+	}
+
+	if original.Fingerprint != "" && original.Fingerprint != fingerprint {
+		span.AddEvent("idempotency key reused")
+		return NewPizzaOrderResponse{}, false, ErrIdempotencyKeyReused
+	}
+
+	span.AddEvent("idempotency key replayed")
+
+	return original.NewPizzaOrderResponse, true, nil
+}
+
+// Release implements IdempotencyStore.
+func (s *NATSIdempotencyStore) Release(ctx context.Context, key string) error {
+	ctx, span := tracer.Start(ctx, "NATSIdempotencyStore.Release")
+	defer span.End()
+
+	err := s.kv.Delete(ctx, bucketKey(key))
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to release idempotency key", "error", err)
+		span.SetStatus(codes.Error, "failed to release idempotency key")
+		span.RecordError(err)
+		return err
+	}
+
+	return nil
+}
+
+// bucketKey hashes the client supplied key, which may contain characters that
+// are not allowed in key-value bucket keys.
+func bucketKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
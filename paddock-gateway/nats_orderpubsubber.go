@@ -3,49 +3,96 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
+	"github.com/taldoflemis/box-box/pacchetto"
 	"github.com/taldoflemis/box-box/pacchetto/telemetry"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// NATSOrderPubSubber publishes orders to JetStream and fans them out to SSE
+// subscribers. Live subscribers share a single ordered consumer per process.
+// Replays of older events read them with a short-lived consumer, deleted as
+// soon as the replay caught up and the subscriber joined the live ones.
 type NATSOrderPubSubber struct {
 	nc          *nats.Conn
 	subject     string
 	streamName  string
-	subs        map[http.Flusher]jetstream.ConsumeContext
 	js          jetstream.JetStream
 	stream      jetstream.Stream
-	channelSize int
+	kv          jetstream.KeyValue
+	broadcaster *Broadcaster
+	liveCons    jetstream.ConsumeContext
+
+	mu sync.Mutex
+	// replays cancels the replays still catching up, by subscriber
+	replays map[http.Flusher]context.CancelFunc
 }
 
-var _ OrderPubSubber = (*NATSOrderPubSubber)(nil)
+// replayBatchSize is how many events a replay fetches at once.
+const replayBatchSize = 256
+
+var (
+	_ OrderPubSubber = (*NATSOrderPubSubber)(nil)
+	_ OrderGetter    = (*NATSOrderPubSubber)(nil)
+)
 
-func NewNATSOrderPubSubber(nc *nats.Conn, subject, streamName string) (*NATSOrderPubSubber, error) {
+func NewNATSOrderPubSubber(
+	nc *nats.Conn,
+	subject, streamName, statusBucket string,
+	duplicateWindow time.Duration,
+	broadcaster *Broadcaster,
+) (*NATSOrderPubSubber, error) {
 	js, err := jetstream.New(nc)
 	if err != nil {
 		slog.Error("failed to create jetstream context", "error", err)
 		return nil, err
 	}
 
-	stream, err := js.CreateStream(context.Background(), jetstream.StreamConfig{
+	stream, err := js.CreateOrUpdateStream(context.Background(), jetstream.StreamConfig{
 		Name:     streamName,
 		Subjects: []string{subject + ".>"},
+		// Publishes carrying an already seen Nats-Msg-Id inside this window are dropped
+		Duplicates: duplicateWindow,
 	})
+	if err != nil {
+		slog.Error("failed to create or update stream", "error", err)
+		return nil, err
+	}
+
+	// The status bucket is a projection of the stream: every service that moves
+	// an order forward also writes its latest state here, keyed by order ID.
+	kv, err := pacchetto.CreateOrderStatusBucket(context.Background(), js, statusBucket)
+	if err != nil {
+		slog.Error("failed to create order status bucket", "error", err)
+		return nil, err
+	}
 
 	pb := &NATSOrderPubSubber{
-		nc:         nc,
-		subject:    subject,
-		streamName: streamName,
-		subs:       make(map[http.Flusher]jetstream.ConsumeContext),
-		stream:     stream,
-		js:         js,
+		nc:          nc,
+		subject:     subject,
+		streamName:  streamName,
+		stream:      stream,
+		kv:          kv,
+		js:          js,
+		broadcaster: broadcaster,
+		replays:     make(map[http.Flusher]context.CancelFunc),
+	}
+
+	pb.liveCons, err = pb.consume(context.Background(), []string{subject + ".>"}, SubscribeOptions{Deliver: DeliverNew}, broadcaster.Publish)
+	if err != nil {
+		slog.Error("failed to consume live orders", "error", err)
+		return nil, err
 	}
 
 	return pb, nil
@@ -56,10 +103,15 @@ func (n *NATSOrderPubSubber) PubOrder(ctx context.Context, order Order) error {
 	defer span.End()
 
 	msg := &nats.Msg{
-		// TODO: Change this to a waiting_payment after we create caixa and maybe higher cardinality subjects
-		Subject: fmt.Sprintf("%s.waiting_to_cook.%s", n.subject, order.OrderID),
+		// caixa charges the order before it reaches the kitchen
+		Subject: fmt.Sprintf("%s.%s.%s", n.subject, OrderStatusWaitingPayment, order.OrderID),
 		Header:  nats.Header{},
 	}
+	// Retries of the same order, from any replica, are deduplicated by the stream
+	msg.Header.Set(jetstream.MsgIDHeader, order.OrderID)
+
+	order.Status = OrderStatusWaitingPayment
+	order.Timestamps = map[string]time.Time{OrderStatusWaitingPayment: time.Now()}
 
 	slog.InfoContext(ctx, "Publishing order to NATS", "header", msg.Header)
 	telemetry.InjectContextToNatsMsg(ctx, msg)
@@ -72,7 +124,7 @@ func (n *NATSOrderPubSubber) PubOrder(ctx context.Context, order Order) error {
 
 	msg.Data = data
 
-	_, err = n.js.PublishMsg(ctx, msg)
+	ack, err := n.js.PublishMsg(ctx, msg)
 	if err != nil {
 		slog.InfoContext(ctx, "Failed to publish order to NATS", "error", err)
 		span.SetStatus(codes.Error, "failed to publish order to NATS")
@@ -80,77 +132,337 @@ func (n *NATSOrderPubSubber) PubOrder(ctx context.Context, order Order) error {
 		return err
 	}
 
+	if ack.Duplicate {
+		slog.InfoContext(ctx, "Order was already published, ignoring duplicate", "order_id", order.OrderID)
+		span.AddEvent("duplicate order publish")
+		return nil
+	}
+
 	slog.InfoContext(ctx, "Published order to NATS", "order_id", order.OrderID)
 
+	// The order is already in the stream, so a failed projection update only
+	// delays lookups until the next stage writes the bucket again.
+	_, err = n.kv.Put(ctx, order.OrderID, data)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to update order status bucket", "order_id", order.OrderID, "error", err)
+		span.RecordError(err)
+	}
+
+	return nil
+}
+
+// PubCancellation implements OrderPubSubber.
+func (n *NATSOrderPubSubber) PubCancellation(ctx context.Context, order Order) error {
+	ctx, span := tracer.Start(ctx, "NATSOrderPubSubber.PubCancellation")
+	defer span.End()
+
+	msg := &nats.Msg{
+		Subject: fmt.Sprintf("%s.%s.%s", n.subject, OrderStatusCancelled, order.OrderID),
+		Header:  nats.Header{},
+	}
+
+	order.Status = OrderStatusCancelled
+	if order.Timestamps == nil {
+		order.Timestamps = make(map[string]time.Time)
+	}
+	order.Timestamps[OrderStatusCancelled] = time.Now()
+
+	telemetry.InjectContextToNatsMsg(ctx, msg)
+
+	data, err := json.Marshal(order)
+	if err != nil {
+		return err
+	}
+
+	msg.Data = data
+
+	_, err = n.js.PublishMsg(ctx, msg)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to publish order cancellation to NATS", "order_id", order.OrderID, "error", err)
+		span.SetStatus(codes.Error, "failed to publish order cancellation to NATS")
+		span.RecordError(err)
+		return err
+	}
+
+	slog.InfoContext(ctx, "Published order cancellation to NATS", "order_id", order.OrderID)
+
+	_, err = n.kv.Put(ctx, order.OrderID, data)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to update order status bucket", "order_id", order.OrderID, "error", err)
+		span.RecordError(err)
+	}
+
 	return nil
 }
 
+// GetOrder implements OrderGetter.
+func (n *NATSOrderPubSubber) GetOrder(ctx context.Context, orderID string) (Order, error) {
+	ctx, span := tracer.Start(ctx, "NATSOrderPubSubber.GetOrder")
+	defer span.End()
+
+	entry, err := n.kv.Get(ctx, orderID)
+	if errors.Is(err, jetstream.ErrKeyNotFound) || errors.Is(err, jetstream.ErrInvalidKey) {
+		return Order{}, ErrOrderNotFound
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to get order from status bucket", "order_id", orderID, "error", err)
+		span.SetStatus(codes.Error, "failed to get order from status bucket")
+		span.RecordError(err)
+		return Order{}, err
+	}
+
+	var order Order
+	err = json.Unmarshal(entry.Value(), &order)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to unmarshal order from status bucket", "order_id", orderID, "error", err)
+		span.SetStatus(codes.Error, "failed to unmarshal order from status bucket")
+		span.RecordError(err)
+		return Order{}, err
+	}
+
+	return order, nil
+}
+
 // SubLiveOrders implements OrderPubSubber.
-func (n *NATSOrderPubSubber) SubLiveOrders(ctx context.Context, flusher http.Flusher) (<-chan Order, error) {
+func (n *NATSOrderPubSubber) SubLiveOrders(ctx context.Context, flusher http.Flusher, opts SubscribeOptions) (<-chan OrderEvent, error) {
 	ctx, span := tracer.Start(ctx, "NATSOrderPubSubber.SubLiveOrders")
 	defer span.End()
 
-	orderCh := make(chan Order, n.channelSize)
+	if opts.Deliver == DeliverNew {
+		slog.InfoContext(ctx, "subscribing to live orders")
+		return n.broadcaster.Subscribe(ctx, flusher, "", true), nil
+	}
+
+	slog.InfoContext(ctx, "replaying orders")
+	return n.replay(ctx, flusher, "", []string{n.subject + ".>"}, opts)
+}
+
+// SubOrderUpdates implements OrderPubSubber.
+func (n *NATSOrderPubSubber) SubOrderUpdates(ctx context.Context, orderID string, flusher http.Flusher, opts SubscribeOptions) (<-chan OrderEvent, error) {
+	ctx, span := tracer.Start(ctx, "NATSOrderPubSubber.SubOrderUpdates")
+	defer span.End()
+
+	if opts.Deliver == DeliverNew {
+		slog.InfoContext(ctx, "subscribing to order updates", "order_id", orderID)
+		return n.broadcaster.Subscribe(ctx, flusher, orderID, true), nil
+	}
+
+	// Every transition of an order is published to <subject>.<status>.<order id>,
+	// so a consumer over all statuses replays its history in order.
+	slog.InfoContext(ctx, "replaying order updates", "order_id", orderID)
+	return n.replay(ctx, flusher, orderID, []string{fmt.Sprintf("%s.*.%s", n.subject, orderID)}, opts)
+}
+
+// UnsubLiveOrders implements OrderPubSubber.
+func (n *NATSOrderPubSubber) UnsubLiveOrders(ctx context.Context, flusher http.Flusher) error {
+	ctx, span := tracer.Start(ctx, "NATSOrderPubSubber.UnsubLiveOrders")
+	defer span.End()
+
+	slog.InfoContext(ctx, "unsubscribing from live orders")
+
+	n.mu.Lock()
+	cancel, ok := n.replays[flusher]
+	delete(n.replays, flusher)
+	n.mu.Unlock()
+
+	if ok {
+		cancel()
+	}
+
+	n.broadcaster.Unsubscribe(ctx, flusher)
+
+	return nil
+}
+
+// Close stops the shared live consumer. Subscribers are expected to be gone.
+func (n *NATSOrderPubSubber) Close() {
+	n.liveCons.Stop()
+}
+
+// replay feeds the subscriber under flusher with the events of subjects from
+// the start in opts, then attaches it to the live subscribers of orderID, or
+// of every order when empty. The events are
+// read by a consumer that only lives until the replay caught up with the shared
+// live consumer, so reconnecting clients do not keep one consumer each.
+func (n *NATSOrderPubSubber) replay(ctx context.Context, flusher http.Flusher, orderID string, subjects []string, opts SubscribeOptions) (<-chan OrderEvent, error) {
+	ctx, span := tracer.Start(ctx, "NATSOrderPubSubber.replay")
+	defer span.End()
+
+	deliverPolicy, startSeq, startTime := jetstreamDeliverPolicy(opts)
 	c, err := n.stream.CreateConsumer(ctx, jetstream.ConsumerConfig{
-		FilterSubject: n.subject + ".>",
-		// We don't want to ack messages, only monitor them
-		AckPolicy: jetstream.AckNonePolicy,
+		FilterSubjects: subjects,
+		DeliverPolicy:  deliverPolicy,
+		OptStartSeq:    startSeq,
+		OptStartTime:   startTime,
+		AckPolicy:      jetstream.AckNonePolicy,
+		MemoryStorage:  true,
+		// Deleted by catchUp, the threshold only covers a gateway crash
+		InactiveThreshold: time.Minute,
 	})
 	if err != nil {
-		slog.ErrorContext(ctx, "failed to create or update consumer", "error", err)
-		span.SetStatus(codes.Error, "failed to create or update consumer")
+		slog.ErrorContext(ctx, "failed to create replay consumer", "error", err)
+		span.SetStatus(codes.Error, "failed to create replay consumer")
 		span.RecordError(err)
 		return nil, err
 	}
 
-	cons, err := c.Consume(func(msg jetstream.Msg) {
-		propagator := otel.GetTextMapPropagator()
-		ctx := propagator.Extract(context.Background(), propagation.HeaderCarrier(msg.Headers()))
+	replayCtx, cancel := context.WithCancel(ctx)
+	n.mu.Lock()
+	n.replays[flusher] = cancel
+	n.mu.Unlock()
 
-		ctx, span := tracer.Start(ctx, "NATSOrderPubSubber.Consume")
-		defer span.End()
+	ch := n.broadcaster.Subscribe(ctx, flusher, orderID, false)
+	go n.catchUp(replayCtx, flusher, subjects, c)
+
+	return ch, nil
+}
 
-		var order Order
-		err := json.Unmarshal(msg.Data(), &order)
+// catchUp offers the events read by c to the subscriber under flusher until
+// nothing is left to read and the subscriber can be attached without missing
+// an event published meanwhile by the live consumer.
+func (n *NATSOrderPubSubber) catchUp(ctx context.Context, flusher http.Flusher, subjects []string, c jetstream.Consumer) {
+	defer func() {
+		n.mu.Lock()
+		delete(n.replays, flusher)
+		n.mu.Unlock()
+
+		err := n.stream.DeleteConsumer(context.Background(), c.CachedInfo().Name)
 		if err != nil {
-			slog.ErrorContext(ctx, "failed to unmarshal order from NATS message", "error", err)
-			span.SetStatus(codes.Error, "failed to unmarshal order from NATS message")
-			span.RecordError(err)
+			slog.WarnContext(ctx, "failed to delete replay consumer", "error", err)
+		}
+	}()
+
+	var through uint64
+	for ctx.Err() == nil {
+		// Everything in the stream up to its last sequence is read once the
+		// consumer has nothing left
+		info, err := n.stream.Info(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to get stream info for replay", "subjects", subjects, "error", err)
+			n.broadcaster.Unsubscribe(ctx, flusher)
+			return
+		}
+		through = max(through, info.State.LastSeq)
+
+		for {
+			batch, err := c.FetchNoWait(replayBatchSize)
+			if err != nil {
+				slog.ErrorContext(ctx, "failed to fetch orders to replay", "subjects", subjects, "error", err)
+				n.broadcaster.Unsubscribe(ctx, flusher)
+				return
+			}
+
+			fetched := 0
+			for msg := range batch.Messages() {
+				fetched++
+				event, ok := orderEvent(ctx, msg)
+				if !ok {
+					continue
+				}
+				through = max(through, event.Sequence)
+				n.broadcaster.Offer(ctx, flusher, event)
+			}
+			if batch.Error() != nil {
+				slog.ErrorContext(ctx, "failed to fetch orders to replay", "subjects", subjects, "error", batch.Error())
+				n.broadcaster.Unsubscribe(ctx, flusher)
+				return
+			}
+			if fetched == 0 {
+				break
+			}
+		}
+
+		if n.broadcaster.Attach(flusher, through) {
+			slog.DebugContext(ctx, "replay caught up with live orders", "subjects", subjects, "sequence", through)
 			return
 		}
+	}
+}
 
-		slog.InfoContext(ctx, "Received order from NATS", "order_id", order.OrderID)
+// consume starts an ordered consumer over subjects and hands every order to handle.
+func (n *NATSOrderPubSubber) consume(
+	ctx context.Context,
+	subjects []string,
+	opts SubscribeOptions,
+	handle func(ctx context.Context, event OrderEvent),
+) (jetstream.ConsumeContext, error) {
+	ctx, span := tracer.Start(ctx, "NATSOrderPubSubber.consume")
+	defer span.End()
 
-		orderCh <- order
+	deliverPolicy, startSeq, startTime := jetstreamDeliverPolicy(opts)
+	c, err := n.js.OrderedConsumer(ctx, n.streamName, jetstream.OrderedConsumerConfig{
+		FilterSubjects: subjects,
+		DeliverPolicy:  deliverPolicy,
+		OptStartSeq:    startSeq,
+		OptStartTime:   startTime,
 	})
 	if err != nil {
-		slog.ErrorContext(ctx, "failed to create consumer", "error", err)
-		span.SetStatus(codes.Error, "failed to create consumer")
+		slog.ErrorContext(ctx, "failed to create ordered consumer", "error", err)
+		span.SetStatus(codes.Error, "failed to create ordered consumer")
 		span.RecordError(err)
 		return nil, err
 	}
 
-	n.subs[flusher] = cons
+	cons, err := c.Consume(func(msg jetstream.Msg) {
+		propagator := otel.GetTextMapPropagator()
+		ctx := propagator.Extract(context.Background(), propagation.HeaderCarrier(msg.Headers()))
 
-	return orderCh, nil
-}
+		ctx, span := tracer.Start(ctx, "NATSOrderPubSubber.Consume")
+		defer span.End()
 
-// UnsubLiveOrders implements OrderPubSubber.
-func (n *NATSOrderPubSubber) UnsubLiveOrders(ctx context.Context, flusher http.Flusher) error {
-	ctx, span := tracer.Start(ctx, "NATSOrderPubSubber.UnsubLiveOrders")
-	defer span.End()
+		event, ok := orderEvent(ctx, msg)
+		if ok {
+			handle(ctx, event)
+		}
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to consume orders", "error", err)
+		span.SetStatus(codes.Error, "failed to consume orders")
+		span.RecordError(err)
+		return nil, err
+	}
 
-	slog.InfoContext(ctx, "unsubscribing from live orders")
+	return cons, nil
+}
 
-	cons, ok := n.subs[flusher]
-	if !ok {
-		slog.WarnContext(ctx, "no subscription found for flusher connection")
-		return nil
+// orderEvent decodes the order in msg, reporting false for malformed messages.
+func orderEvent(ctx context.Context, msg jetstream.Msg) (OrderEvent, bool) {
+	var order Order
+	err := json.Unmarshal(msg.Data(), &order)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to unmarshal order from NATS message", "error", err)
+		span := trace.SpanFromContext(ctx)
+		span.SetStatus(codes.Error, "failed to unmarshal order from NATS message")
+		span.RecordError(err)
+		return OrderEvent{}, false
 	}
 
-	cons.Stop()
+	slog.DebugContext(ctx, "Received order from NATS", "order_id", order.OrderID)
 
-	delete(n.subs, flusher)
+	return OrderEvent{Order: order, Sequence: streamSequence(msg)}, true
+}
 
-	return nil
+// jetstreamDeliverPolicy translates SubscribeOptions to a consumer deliver policy
+// and its optional start sequence or start time.
+func jetstreamDeliverPolicy(opts SubscribeOptions) (jetstream.DeliverPolicy, uint64, *time.Time) {
+	switch opts.Deliver {
+	case DeliverAll:
+		return jetstream.DeliverAllPolicy, 0, nil
+	case DeliverByStartSequence:
+		return jetstream.DeliverByStartSequencePolicy, opts.StartSequence, nil
+	case DeliverByStartTime:
+		return jetstream.DeliverByStartTimePolicy, 0, &opts.StartTime
+	default:
+		return jetstream.DeliverNewPolicy, 0, nil
+	}
+}
+
+// streamSequence returns the position of msg in the stream, or 0 when unknown.
+func streamSequence(msg jetstream.Msg) uint64 {
+	meta, err := msg.Metadata()
+	if err != nil {
+		return 0
+	}
+
+	return meta.Sequence.Stream
 }
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const outboxFileExt = ".json"
+
+// FileOrderOutbox keeps accepted orders as JSON files in a local directory
+// while JetStream is unavailable and re-publishes them once it is back.
+//
+// Each order is a single file named after the time it was enqueued, so the
+// relay publishes them in the same order they were accepted. Orders keep their
+// ID, which is also the Nats-Msg-Id, so a relay that races with a publish that
+// actually succeeded is dropped by the stream duplicate window.
+type FileOrderOutbox struct {
+	dir       string
+	publisher OrderPubSubber
+	connected func() bool
+	interval  time.Duration
+}
+
+var _ OrderOutbox = (*FileOrderOutbox)(nil)
+
+func NewFileOrderOutbox(
+	dir string,
+	publisher OrderPubSubber,
+	connected func() bool,
+	interval time.Duration,
+) (*FileOrderOutbox, error) {
+	err := os.MkdirAll(dir, 0o750)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create outbox directory: %w", err)
+	}
+
+	return &FileOrderOutbox{
+		dir:       dir,
+		publisher: publisher,
+		connected: connected,
+		interval:  interval,
+	}, nil
+}
+
+// Enqueue implements OrderOutbox.
+func (o *FileOrderOutbox) Enqueue(ctx context.Context, order Order) error {
+	ctx, span := tracer.Start(ctx, "FileOrderOutbox.Enqueue")
+	defer span.End()
+
+	data, err := json.Marshal(order)
+	if err != nil {
+		return fmt.Errorf("failed to marshal order: %w", err)
+	}
+
+	// Write to a temporary file first so the relay never reads half an order
+	tmp, err := os.CreateTemp(o.dir, "enqueue-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create outbox file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	closeErr := tmp.Close()
+	if err != nil {
+		return fmt.Errorf("failed to write outbox file: %w", err)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("failed to close outbox file: %w", closeErr)
+	}
+
+	name := fmt.Sprintf("%020d-%s%s", time.Now().UnixNano(), order.OrderID, outboxFileExt)
+	err = os.Rename(tmp.Name(), filepath.Join(o.dir, name))
+	if err != nil {
+		return fmt.Errorf("failed to commit outbox file: %w", err)
+	}
+
+	slog.InfoContext(ctx, "order kept in outbox", slog.String("order_id", order.OrderID))
+
+	return nil
+}
+
+// Relay re-publishes pending orders every interval until ctx is done.
+func (o *FileOrderOutbox) Relay(ctx context.Context) {
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+
+	for {
+		o.relayPending(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// relayPending publishes every pending order, oldest first. It stops at the
+// first failure so orders are not reordered.
+func (o *FileOrderOutbox) relayPending(ctx context.Context) {
+	if !o.connected() {
+		return
+	}
+
+	entries, err := os.ReadDir(o.dir)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to list outbox", slog.String("error", err.Error()))
+		return
+	}
+
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), outboxFileExt) {
+			continue
+		}
+
+		path := filepath.Join(o.dir, entry.Name())
+		err = o.relay(ctx, path)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to relay order from outbox, will retry",
+				slog.String("file", entry.Name()),
+				slog.String("error", err.Error()),
+			)
+			return
+		}
+	}
+}
+
+func (o *FileOrderOutbox) relay(ctx context.Context, path string) error {
+	ctx, span := tracer.Start(ctx, "FileOrderOutbox.relay")
+	defer span.End()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var order Order
+	err = json.Unmarshal(data, &order)
+	if err != nil {
+		// A broken file would block the whole outbox, set it aside for inspection
+		slog.ErrorContext(ctx, "discarding unreadable outbox file", slog.String("file", path), slog.String("error", err.Error()))
+		return os.Rename(path, path+".corrupt")
+	}
+
+	err = o.publisher.PubOrder(ctx, order)
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "relayed order from outbox", slog.String("order_id", order.OrderID))
+
+	return os.Remove(path)
+}
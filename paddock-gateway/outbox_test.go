@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type unreachablePubSubber struct {
+	*GoChannelOrderPubSubber
+	down bool
+}
+
+func (u *unreachablePubSubber) PubOrder(ctx context.Context, order Order) error {
+	if u.down {
+		return errors.New("nats: no responders available for request")
+	}
+	return u.GoChannelOrderPubSubber.PubOrder(ctx, order)
+}
+
+func TestFileOrderOutboxRelay(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	dir := t.TempDir()
+	publisher := &unreachablePubSubber{GoChannelOrderPubSubber: NewGoChannelOrderPubSubber(), down: true}
+	outbox, err := NewFileOrderOutbox(dir, publisher, func() bool { return true }, 0)
+	require.NoError(t, err)
+
+	require.NoError(t, outbox.Enqueue(ctx, Order{OrderID: "first"}))
+	require.NoError(t, outbox.Enqueue(ctx, Order{OrderID: "second"}))
+
+	// Act
+	outbox.relayPending(ctx)
+	pendingWhileDown, err := os.ReadDir(dir)
+	require.NoError(t, err)
+
+	publisher.down = false
+	outbox.relayPending(ctx)
+	pendingAfterRelay, err := os.ReadDir(dir)
+	require.NoError(t, err)
+
+	// Assert
+	assert.Len(t, pendingWhileDown, 2)
+	assert.Empty(t, pendingAfterRelay)
+	for _, orderID := range []string{"first", "second"} {
+		_, err := publisher.GetOrder(ctx, orderID)
+		assert.NoError(t, err, orderID)
+	}
+}
+
+func TestOrderNewPizzaPublishFailure(t *testing.T) {
+	body := `{"size":"large","toppings":["pepperoni"],"destination":"Garage #16","username":"charles_leclerc"}`
+
+	tests := []struct {
+		name           string
+		withOutbox     bool
+		wantStatus     int
+		wantRetryAfter string
+	}{
+		{
+			name:           "without outbox the client is told to retry",
+			wantStatus:     http.StatusServiceUnavailable,
+			wantRetryAfter: "5",
+		},
+		{
+			name:       "with outbox the order is accepted",
+			withOutbox: true,
+			wantStatus: http.StatusAccepted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			e := echo.New()
+			settings := &Settings{Orders: OrdersSettings{RetryAfterInSeconds: 5}}
+			publisher := &unreachablePubSubber{GoChannelOrderPubSubber: NewGoChannelOrderPubSubber(), down: true}
+
+			var outbox OrderOutbox
+			if tt.withOutbox {
+				fileOutbox, err := NewFileOrderOutbox(t.TempDir(), publisher, func() bool { return false }, 0)
+				require.NoError(t, err)
+				outbox = fileOutbox
+			}
+			NewMainHandler(e, settings, MainHandlerDeps{OrderPubSubber: publisher, OrderGetter: publisher, Outbox: outbox})
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/order", strings.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			// Act
+			e.ServeHTTP(rec, req)
+
+			// Assert
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, tt.wantRetryAfter, rec.Header().Get(echo.HeaderRetryAfter))
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+)
+
+// Pricer prices orders with the rules of the pricing settings. Every pizza
+// costs its size, plus its border, plus each of its toppings.
+type Pricer struct {
+	settings PricingSettings
+}
+
+func NewPricer(settings PricingSettings) *Pricer {
+	return &Pricer{settings: settings}
+}
+
+// Currency is the currency of every price, in its minor units.
+func (p *Pricer) Currency() string {
+	return p.settings.Currency
+}
+
+// Price sets the unit price and total of every item and returns the order total.
+func (p *Pricer) Price(items []OrderItem) (int64, error) {
+	var total int64
+	for i := range items {
+		unitPrice, err := p.unitPrice(items[i])
+		if err != nil {
+			return 0, fmt.Errorf("items[%d]: %w", i, err)
+		}
+
+		items[i].UnitPrice = unitPrice
+		items[i].Total = unitPrice * int64(items[i].Quantity)
+		total += items[i].Total
+	}
+
+	return total, nil
+}
+
+func (p *Pricer) unitPrice(item OrderItem) (int64, error) {
+	price, ok := p.settings.Sizes[item.Size]
+	if !ok {
+		return 0, fmt.Errorf("no price for size %s", item.Size)
+	}
+
+	// Borders without a price are free, like none
+	price += p.settings.Borders[item.Border]
+
+	for _, topping := range item.Toppings {
+		toppingPrice, ok := p.settings.Toppings[topping]
+		if !ok {
+			toppingPrice = p.settings.ToppingDefault
+		}
+		price += toppingPrice
+	}
+
+	return price, nil
+}
+
+// formatPrice renders minor units with two decimals, 1450 is 14.50.
+func formatPrice(price int64) string {
+	return fmt.Sprintf("%d.%02d", price/100, price%100)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPricerPrice(t *testing.T) {
+	pricer := NewPricer(PricingSettings{
+		Currency:       "EUR",
+		Sizes:          map[string]int64{"small": 800, "large": 1400},
+		Borders:        map[string]int64{"cheddar": 250},
+		ToppingDefault: 150,
+		Toppings:       map[string]int64{"basil": 50},
+	})
+
+	tests := []struct {
+		name           string
+		items          []OrderItem
+		wantUnitPrices []int64
+		wantTotal      int64
+		wantErr        bool
+	}{
+		{
+			name: "size, border and toppings add up per pizza",
+			items: []OrderItem{
+				{Size: "large", Border: "cheddar", Toppings: []string{"pepperoni", "basil"}, Quantity: 2},
+				{Size: "small", Border: "none", Toppings: []string{"basil"}, Quantity: 1},
+			},
+			wantUnitPrices: []int64{1400 + 250 + 150 + 50, 800 + 50},
+			wantTotal:      2*1850 + 850,
+		},
+		{
+			name:    "size without a price",
+			items:   []OrderItem{{Size: "medium", Quantity: 1}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			total, err := pricer.Price(tt.items)
+
+			// Assert
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantTotal, total)
+			for i, item := range tt.items {
+				assert.Equal(t, tt.wantUnitPrices[i], item.UnitPrice)
+				assert.Equal(t, tt.wantUnitPrices[i]*int64(item.Quantity), item.Total)
+			}
+		})
+	}
+}
+
+func TestReceiptWriteText(t *testing.T) {
+	// Arrange
+	receipt := NewReceipt(Order{
+		OrderID:     "123",
+		Username:    "charles_leclerc",
+		Destination: "Garage #16",
+		OrderedAt:   time.Date(2026, 5, 24, 15, 0, 0, 0, time.UTC),
+		Status:      OrderStatusWaitingDelivery,
+		Items: []OrderItem{
+			{Size: "large", Border: "cream_cheese", Toppings: []string{"pepperoni", "basil"}, Quantity: 2, UnitPrice: 1850, Total: 3700},
+			{Size: "small", Border: "none", Toppings: []string{"basil"}, Quantity: 1, UnitPrice: 850, Total: 850},
+		},
+		Total:    4550,
+		Currency: "EUR",
+	})
+	var sb strings.Builder
+
+	// Act
+	err := receipt.WriteText(&sb)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, `Order        123
+Customer     charles_leclerc
+Destination  Garage #16
+Ordered at   2026-05-24T15:00:00Z
+Status       waiting_delivery
+
+2 x large, cream cheese border: pepperoni, basil     18.50     37.00
+1 x small: basil                                      8.50      8.50
+Total EUR                                                      45.50
+`, sb.String())
+}
+
+func TestReceiptWriteTextDiscount(t *testing.T) {
+	// Arrange
+	receipt := NewReceipt(Order{
+		OrderID:     "123",
+		Username:    "charles_leclerc",
+		Destination: "Garage #16",
+		OrderedAt:   time.Date(2026, 5, 24, 15, 0, 0, 0, time.UTC),
+		Status:      OrderStatusWaitingDelivery,
+		Items: []OrderItem{
+			{Size: "small", Border: "none", Toppings: []string{"basil"}, Quantity: 1, UnitPrice: 850, Total: 850},
+		},
+		Subtotal:  850,
+		PromoCode: "FORZAFERRARI",
+		Discount:  170,
+		Total:     680,
+		Currency:  "EUR",
+	})
+	var sb strings.Builder
+
+	// Act
+	err := receipt.WriteText(&sb)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, `Order        123
+Customer     charles_leclerc
+Destination  Garage #16
+Ordered at   2026-05-24T15:00:00Z
+Status       waiting_delivery
+
+1 x small: basil           8.50      8.50
+Subtotal                             8.50
+Discount FORZAFERRARI               -1.70
+Total EUR                            6.80
+`, sb.String())
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+// ProblemDetails is an RFC 7807 error response.
+type ProblemDetails struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes why a single request field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func newProblem(c echo.Context, status int, detail string) ProblemDetails {
+	return ProblemDetails{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: c.Request().URL.Path,
+	}
+}
+
+func writeProblem(c echo.Context, problem ProblemDetails) error {
+	// c.JSON keeps an already set content type
+	c.Response().Header().Set(echo.HeaderContentType, MIMEApplicationProblemJSON)
+	return c.JSON(problem.Status, problem)
+}
+
+// problemHTTPErrorHandler renders every error that reaches echo as problem+json,
+// so clients only have one error format to parse.
+func problemHTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	status := http.StatusInternalServerError
+	detail := ""
+
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		status = he.Code
+		if msg, ok := he.Message.(string); ok {
+			detail = msg
+		}
+	}
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(status)
+	} else {
+		err = writeProblem(c, newProblem(c, status, detail))
+	}
+	if err != nil {
+		slog.ErrorContext(c.Request().Context(), "failed to write problem response", slog.String("error", err.Error()))
+	}
+}
+
+// requestValidator plugs go-playground/validator into echo and reports fields
+// by their JSON name.
+type requestValidator struct {
+	validate *validator.Validate
+}
+
+var _ echo.Validator = (*requestValidator)(nil)
+
+func newRequestValidator() *requestValidator {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	return &requestValidator{validate: validate}
+}
+
+// Validate implements echo.Validator.
+func (v *requestValidator) Validate(i any) error {
+	return v.validate.Struct(i)
+}
+
+// validationProblem turns validator errors into a 422 problem with one entry per field.
+func validationProblem(c echo.Context, err error) (ProblemDetails, bool) {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return ProblemDetails{}, false
+	}
+
+	problem := newProblem(c, http.StatusUnprocessableEntity, "The request body has invalid fields")
+	for _, fe := range validationErrors {
+		// Drop the struct name, clients only know the JSON paths
+		_, field, _ := strings.Cut(fe.Namespace(), ".")
+		problem.Errors = append(problem.Errors, FieldError{
+			Field:   field,
+			Rule:    fe.Tag(),
+			Message: fieldErrorMessage(fe),
+		})
+	}
+
+	return problem, true
+}
+
+func fieldErrorMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "min":
+		if fe.Kind() == reflect.Slice {
+			return "must have at least " + fe.Param() + " element(s)"
+		}
+		return "must be at least " + fe.Param()
+	case "max":
+		if fe.Kind() == reflect.Slice {
+			return "must have at most " + fe.Param() + " element(s)"
+		}
+		return "must be at most " + fe.Param()
+	case "required_without":
+		return "is required without " + strings.ToLower(fe.Param())
+	case "excluded_with":
+		return "must be empty when " + strings.ToLower(fe.Param()) + " are given"
+	default:
+		return "failed the " + fe.Tag() + " rule"
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/taldoflemis/box-box/pacchetto"
+)
+
+func TestOrderNewPizzaValidation(t *testing.T) {
+	// Arrange
+	e := echo.New()
+	settings := &Settings{
+		HTTP: pacchetto.HTTPSettings{
+			CORS: pacchetto.CORSSettings{
+				Origins: []string{"http://*"},
+				Methods: []string{"POST"},
+				Headers: []string{"Content-Type"},
+			},
+		},
+	}
+	pubSubber := NewGoChannelOrderPubSubber()
+	NewMainHandler(e, settings, MainHandlerDeps{OrderPubSubber: pubSubber, OrderGetter: pubSubber})
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantFields []string
+	}{
+		{
+			name:       "valid order",
+			body:       `{"size":"large","toppings":["pepperoni"],"destination":"Garage #16","username":"charles_leclerc"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "unknown size and no toppings",
+			body:       `{"size":"huge","toppings":[],"destination":"Garage #16","username":"charles_leclerc"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantFields: []string{"size", "toppings"},
+		},
+		{
+			name:       "unknown border",
+			body:       `{"size":"large","border":"catupiry","toppings":["pepperoni"],"destination":"Garage #16","username":"charles_leclerc"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantFields: []string{"border"},
+		},
+		{
+			name:       "missing username and destination",
+			body:       `{"size":"small","toppings":["basil",""]}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantFields: []string{"toppings[1]", "destination", "username"},
+		},
+		{
+			name:       "line items",
+			body:       `{"items":[{"size":"large","toppings":["pepperoni"],"quantity":3},{"size":"small","border":"cheddar","toppings":["basil"]}],"destination":"Garage #16","username":"charles_leclerc"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "line items mixed with a single pizza",
+			body:       `{"size":"large","items":[{"size":"huge","toppings":["basil"],"quantity":21}],"destination":"Garage #16","username":"charles_leclerc"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantFields: []string{"size", "items[0].size", "items[0].quantity"},
+		},
+		{
+			name:       "no pizza",
+			body:       `{"destination":"Garage #16","username":"charles_leclerc"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantFields: []string{"size", "toppings", "items"},
+		},
+		{
+			name:       "malformed body",
+			body:       `{"size":`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/v1/order", strings.NewReader(tt.body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		// Act
+		e.ServeHTTP(rec, req)
+
+		// Assert
+		assert.Equal(t, tt.wantStatus, rec.Code, tt.name)
+		if tt.wantStatus == http.StatusOK {
+			continue
+		}
+
+		assert.Equal(t, MIMEApplicationProblemJSON, rec.Header().Get(echo.HeaderContentType), tt.name)
+
+		var problem ProblemDetails
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem), tt.name)
+		assert.Equal(t, tt.wantStatus, problem.Status, tt.name)
+
+		fields := make([]string, 0, len(problem.Errors))
+		for _, fe := range problem.Errors {
+			fields = append(fields, fe.Field)
+		}
+		assert.ElementsMatch(t, tt.wantFields, fields, tt.name)
+	}
+}
@@ -0,0 +1,430 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var (
+	ErrPromoCodeNotFound  = errors.New("promo code not found")
+	ErrPromoCodeNotValid  = errors.New("promo code is not valid at this time")
+	ErrPromoCodeExhausted = errors.New("promo code has no redemptions left")
+	ErrPromoCodeUsedUp    = errors.New("promo code was redeemed the maximum number of times by this user")
+)
+
+// PromoCode takes PercentOff percent or AmountOff minor units off the order
+// subtotal. Zero ValidFrom, ValidUntil, PerUserLimit and MaxRedemptions mean
+// no limit.
+type PromoCode struct {
+	Code           string    `mapstructure:"code" json:"code" validate:"required,alphanum"`
+	Description    string    `mapstructure:"description" json:"description,omitempty"` // e.g., "Ferrari won, 20% off"
+	PercentOff     int       `mapstructure:"percent-off" json:"percent_off,omitempty" validate:"required_without=AmountOff,excluded_with=AmountOff,omitempty,min=1,max=100"`
+	AmountOff      int64     `mapstructure:"amount-off" json:"amount_off,omitempty" validate:"omitempty,min=1"`
+	ValidFrom      time.Time `mapstructure:"valid-from" json:"valid_from,omitzero"`
+	ValidUntil     time.Time `mapstructure:"valid-until" json:"valid_until,omitzero"`
+	PerUserLimit   int       `mapstructure:"per-user-limit" json:"per_user_limit,omitempty" validate:"min=0"`
+	MaxRedemptions int       `mapstructure:"max-redemptions" json:"max_redemptions,omitempty" validate:"min=0"`
+}
+
+// normalizePromoCode makes codes case insensitive, FERRARI20 and ferrari20 are the same code.
+func normalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// ValidAt reports whether the code can be redeemed at t.
+func (p PromoCode) ValidAt(t time.Time) bool {
+	if !p.ValidFrom.IsZero() && t.Before(p.ValidFrom) {
+		return false
+	}
+	if !p.ValidUntil.IsZero() && !t.Before(p.ValidUntil) {
+		return false
+	}
+	return true
+}
+
+// Discount is the amount taken off subtotal, never more than subtotal.
+// Percentages are rounded half up to the minor unit.
+func (p PromoCode) Discount(subtotal int64) int64 {
+	discount := p.AmountOff
+	if p.PercentOff > 0 {
+		discount = (subtotal*int64(p.PercentOff) + 50) / 100
+	}
+	return min(discount, subtotal)
+}
+
+// PromoStore looks up promo codes and counts their redemptions.
+type PromoStore interface {
+	// Lookup returns the code, or ErrPromoCodeNotFound.
+	Lookup(ctx context.Context, code string) (PromoCode, error)
+	// Redemptions returns how often promo was redeemed in total and by username.
+	Redemptions(ctx context.Context, promo PromoCode, username string) (total int, byUser int, err error)
+	// Redeem counts one redemption of promo by username. It fails with
+	// ErrPromoCodeExhausted or ErrPromoCodeUsedUp when that would exceed the
+	// code limits.
+	Redeem(ctx context.Context, promo PromoCode, username string) error
+	// Release gives back a redemption of an order that was never placed.
+	Release(ctx context.Context, promo PromoCode, username string) error
+}
+
+func promoCodesByCode(codes []PromoCode) map[string]PromoCode {
+	byCode := make(map[string]PromoCode, len(codes))
+	for _, promo := range codes {
+		promo.Code = normalizePromoCode(promo.Code)
+		byCode[promo.Code] = promo
+	}
+	return byCode
+}
+
+// MemoryPromoStore serves the configured codes and counts redemptions in a
+// single gateway process.
+type MemoryPromoStore struct {
+	codes map[string]PromoCode
+
+	mu     sync.Mutex
+	totals map[string]int
+	byUser map[string]int
+}
+
+var _ PromoStore = (*MemoryPromoStore)(nil)
+
+func NewMemoryPromoStore(codes []PromoCode) *MemoryPromoStore {
+	return &MemoryPromoStore{
+		codes:  promoCodesByCode(codes),
+		totals: make(map[string]int),
+		byUser: make(map[string]int),
+	}
+}
+
+// Lookup implements PromoStore.
+func (m *MemoryPromoStore) Lookup(_ context.Context, code string) (PromoCode, error) {
+	promo, ok := m.codes[normalizePromoCode(code)]
+	if !ok {
+		return PromoCode{}, ErrPromoCodeNotFound
+	}
+	return promo, nil
+}
+
+// Redemptions implements PromoStore.
+func (m *MemoryPromoStore) Redemptions(_ context.Context, promo PromoCode, username string) (int, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.totals[promo.Code], m.byUser[promo.Code+"\x00"+username], nil
+}
+
+// Redeem implements PromoStore.
+func (m *MemoryPromoStore) Redeem(_ context.Context, promo PromoCode, username string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	userKey := promo.Code + "\x00" + username
+	if promo.MaxRedemptions > 0 && m.totals[promo.Code] >= promo.MaxRedemptions {
+		return ErrPromoCodeExhausted
+	}
+	if promo.PerUserLimit > 0 && m.byUser[userKey] >= promo.PerUserLimit {
+		return ErrPromoCodeUsedUp
+	}
+
+	m.totals[promo.Code]++
+	m.byUser[userKey]++
+	return nil
+}
+
+// Release implements PromoStore.
+func (m *MemoryPromoStore) Release(_ context.Context, promo PromoCode, username string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	userKey := promo.Code + "\x00" + username
+	m.totals[promo.Code] = max(0, m.totals[promo.Code]-1)
+	m.byUser[userKey] = max(0, m.byUser[userKey]-1)
+	return nil
+}
+
+// NATSPromoStore serves the configured codes together with the codes kept in
+// a key-value bucket, which can be added during a race weekend without
+// restarting the gateway. A code in the bucket replaces a configured code
+// with the same name. Redemptions are counted in a second bucket shared by
+// every gateway replica, with optimistic concurrency on the key revision.
+type NATSPromoStore struct {
+	configured  map[string]PromoCode
+	codes       jetstream.KeyValue
+	redemptions jetstream.KeyValue
+	validate    *validator.Validate
+}
+
+var _ PromoStore = (*NATSPromoStore)(nil)
+
+const promoMaxAttempts = 5
+
+func NewNATSPromoStore(nc *nats.Conn, codesBucket, redemptionsBucket string, configured []PromoCode) (*NATSPromoStore, error) {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		slog.Error("failed to create jetstream context", "error", err)
+		return nil, err
+	}
+
+	codes, err := js.CreateOrUpdateKeyValue(context.Background(), jetstream.KeyValueConfig{
+		Bucket:      codesBucket,
+		Description: "Promo codes by code, as JSON",
+	})
+	if err != nil {
+		slog.Error("failed to create promo codes bucket", "error", err)
+		return nil, err
+	}
+
+	redemptions, err := js.CreateOrUpdateKeyValue(context.Background(), jetstream.KeyValueConfig{
+		Bucket:      redemptionsBucket,
+		Description: "Promo code redemptions in total and per user",
+	})
+	if err != nil {
+		slog.Error("failed to create promo redemptions bucket", "error", err)
+		return nil, err
+	}
+
+	return &NATSPromoStore{
+		configured:  promoCodesByCode(configured),
+		codes:       codes,
+		redemptions: redemptions,
+		validate:    validator.New(),
+	}, nil
+}
+
+// Lookup implements PromoStore.
+func (n *NATSPromoStore) Lookup(ctx context.Context, code string) (PromoCode, error) {
+	ctx, span := tracer.Start(ctx, "NATSPromoStore.Lookup")
+	defer span.End()
+
+	code = normalizePromoCode(code)
+	entry, err := n.codes.Get(ctx, code)
+	switch {
+	case errors.Is(err, jetstream.ErrKeyNotFound), errors.Is(err, jetstream.ErrInvalidKey):
+		promo, ok := n.configured[code]
+		if !ok {
+			return PromoCode{}, ErrPromoCodeNotFound
+		}
+		return promo, nil
+	case err != nil:
+		return PromoCode{}, err
+	}
+
+	var promo PromoCode
+	err = json.Unmarshal(entry.Value(), &promo)
+	if err == nil {
+		err = n.validate.Struct(promo)
+	}
+	if err != nil {
+		// A broken code is a mistake of whoever wrote it, not of the customer
+		slog.ErrorContext(ctx, "ignoring invalid promo code", slog.String("code", code), slog.String("error", err.Error()))
+		return PromoCode{}, ErrPromoCodeNotFound
+	}
+	promo.Code = code
+
+	return promo, nil
+}
+
+func promoTotalKey(promo PromoCode) string {
+	return "total." + promo.Code
+}
+
+func promoUserKey(promo PromoCode, username string) string {
+	return "user." + promo.Code + "." + bucketKey(username)
+}
+
+// Redemptions implements PromoStore.
+func (n *NATSPromoStore) Redemptions(ctx context.Context, promo PromoCode, username string) (int, int, error) {
+	ctx, span := tracer.Start(ctx, "NATSPromoStore.Redemptions")
+	defer span.End()
+
+	total, _, err := n.count(ctx, promoTotalKey(promo))
+	if err != nil {
+		return 0, 0, err
+	}
+	byUser, _, err := n.count(ctx, promoUserKey(promo, username))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return total, byUser, nil
+}
+
+// Redeem implements PromoStore. The total is counted first, when the user is
+// over the limit the total is given back.
+func (n *NATSPromoStore) Redeem(ctx context.Context, promo PromoCode, username string) error {
+	ctx, span := tracer.Start(ctx, "NATSPromoStore.Redeem")
+	defer span.End()
+
+	err := n.add(ctx, promoTotalKey(promo), 1, promo.MaxRedemptions, ErrPromoCodeExhausted)
+	if err != nil {
+		return err
+	}
+
+	err = n.add(ctx, promoUserKey(promo, username), 1, promo.PerUserLimit, ErrPromoCodeUsedUp)
+	if err != nil {
+		releaseErr := n.add(ctx, promoTotalKey(promo), -1, 0, nil)
+		if releaseErr != nil {
+			slog.ErrorContext(ctx, "failed to give back promo redemption", slog.String("code", promo.Code), slog.String("error", releaseErr.Error()))
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Release implements PromoStore.
+func (n *NATSPromoStore) Release(ctx context.Context, promo PromoCode, username string) error {
+	ctx, span := tracer.Start(ctx, "NATSPromoStore.Release")
+	defer span.End()
+
+	return errors.Join(
+		n.add(ctx, promoTotalKey(promo), -1, 0, nil),
+		n.add(ctx, promoUserKey(promo, username), -1, 0, nil),
+	)
+}
+
+// count reads a redemption counter and its revision, zero when missing.
+func (n *NATSPromoStore) count(ctx context.Context, key string) (int, uint64, error) {
+	entry, err := n.redemptions.Get(ctx, key)
+	if errors.Is(err, jetstream.ErrKeyNotFound) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	count, err := strconv.Atoi(string(entry.Value()))
+	if err != nil {
+		return 0, 0, fmt.Errorf("promo redemptions %s: %w", key, err)
+	}
+
+	return count, entry.Revision(), nil
+}
+
+// add changes the counter at key by delta, failing with errLimit when a
+// positive limit would be exceeded. Counters never go below zero.
+func (n *NATSPromoStore) add(ctx context.Context, key string, delta int, limit int, errLimit error) error {
+	for range promoMaxAttempts {
+		count, revision, err := n.count(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		if limit > 0 && count+delta > limit {
+			return errLimit
+		}
+
+		data := []byte(strconv.Itoa(max(0, count+delta)))
+		if revision == 0 {
+			_, err = n.redemptions.Create(ctx, key, data)
+		} else {
+			_, err = n.redemptions.Update(ctx, key, data, revision)
+		}
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, jetstream.ErrKeyExists) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("promo redemptions %s kept changing after %d attempts", key, promoMaxAttempts)
+}
+
+// Promotions applies promo codes to priced orders.
+type Promotions struct {
+	store    PromoStore
+	redeemed metric.Int64Counter
+}
+
+func NewPromotions(store PromoStore) (*Promotions, error) {
+	redeemed, err := meter.Int64Counter(
+		"paddock_gateway.promo.redemptions",
+		metric.WithDescription("Number of orders placed with a promo code"),
+		metric.WithUnit("{redemption}"),
+	)
+	if err != nil {
+		slog.Error("failed to create promo redemptions counter", slog.Any("err", err))
+		return nil, err
+	}
+
+	return &Promotions{store: store, redeemed: redeemed}, nil
+}
+
+// Check returns the promo code and the discount it gives on subtotal for
+// username at now, without redeeming it.
+func (p *Promotions) Check(ctx context.Context, code string, username string, subtotal int64, now time.Time) (PromoCode, int64, error) {
+	ctx, span := tracer.Start(ctx, "Promotions.Check")
+	defer span.End()
+
+	promo, err := p.store.Lookup(ctx, code)
+	if err != nil {
+		return PromoCode{}, 0, err
+	}
+
+	if !promo.ValidAt(now) {
+		return PromoCode{}, 0, ErrPromoCodeNotValid
+	}
+
+	if promo.MaxRedemptions > 0 || promo.PerUserLimit > 0 {
+		total, byUser, err := p.store.Redemptions(ctx, promo, username)
+		if err != nil {
+			return PromoCode{}, 0, err
+		}
+		if promo.MaxRedemptions > 0 && total >= promo.MaxRedemptions {
+			return PromoCode{}, 0, ErrPromoCodeExhausted
+		}
+		if promo.PerUserLimit > 0 && byUser >= promo.PerUserLimit {
+			return PromoCode{}, 0, ErrPromoCodeUsedUp
+		}
+	}
+
+	return promo, promo.Discount(subtotal), nil
+}
+
+// Redeem counts a redemption of promo by username.
+func (p *Promotions) Redeem(ctx context.Context, promo PromoCode, username string) error {
+	err := p.store.Redeem(ctx, promo, username)
+	if err != nil {
+		return err
+	}
+
+	p.redeemed.Add(ctx, 1, metric.WithAttributes(attribute.String("promo.code", promo.Code)))
+	return nil
+}
+
+// Release gives back a redemption of an order that was never placed.
+func (p *Promotions) Release(ctx context.Context, promo PromoCode, username string) error {
+	return p.store.Release(ctx, promo, username)
+}
+
+// promoFieldError explains to the customer why a promo code was not applied,
+// ok is false for errors that are not the customer's.
+func promoFieldError(err error) (FieldError, bool) {
+	fe := FieldError{Field: "promo_code"}
+	switch {
+	case errors.Is(err, ErrPromoCodeNotFound):
+		fe.Rule, fe.Message = "promo", "is not a valid promo code"
+	case errors.Is(err, ErrPromoCodeNotValid):
+		fe.Rule, fe.Message = "promo_window", "is not valid at this time"
+	case errors.Is(err, ErrPromoCodeExhausted):
+		fe.Rule, fe.Message = "promo_cap", "has no redemptions left"
+	case errors.Is(err, ErrPromoCodeUsedUp):
+		fe.Rule, fe.Message = "promo_user_limit", "was already used the maximum number of times"
+	default:
+		return FieldError{}, false
+	}
+	return fe, true
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromoCodeDiscount(t *testing.T) {
+	tests := []struct {
+		name     string
+		promo    PromoCode
+		subtotal int64
+		want     int64
+	}{
+		{name: "percentage", promo: PromoCode{PercentOff: 20}, subtotal: 4550, want: 910},
+		{name: "percentage rounds half up", promo: PromoCode{PercentOff: 15}, subtotal: 850, want: 128},
+		{name: "fixed amount", promo: PromoCode{AmountOff: 500}, subtotal: 4550, want: 500},
+		{name: "never more than the subtotal", promo: PromoCode{AmountOff: 5000}, subtotal: 850, want: 850},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			got := tt.promo.Discount(tt.subtotal)
+
+			// Assert
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestOrderNewPizzaPromoCode(t *testing.T) {
+	// Arrange
+	e := echo.New()
+	pubSubber := NewGoChannelOrderPubSubber()
+	pricer := NewPricer(PricingSettings{Currency: "EUR", Sizes: map[string]int64{"large": 1400}, ToppingDefault: 100})
+	promotions, err := NewPromotions(NewMemoryPromoStore([]PromoCode{
+		{Code: "FORZAFERRARI", PercentOff: 20, PerUserLimit: 1, MaxRedemptions: 2},
+		{Code: "MONZA", AmountOff: 500, ValidUntil: time.Now().Add(-time.Hour)},
+	}))
+	require.NoError(t, err)
+	NewMainHandler(e, &Settings{}, MainHandlerDeps{OrderPubSubber: pubSubber, OrderGetter: pubSubber, Pricer: pricer, Promotions: promotions})
+
+	order := func(username, promoCode string) *httptest.ResponseRecorder {
+		body := `{"size":"large","toppings":["pepperoni"],"destination":"Garage #16","username":"` + username + `","promo_code":"` + promoCode + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/v1/order", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Act
+	first := order("charles_leclerc", "forzaferrari")
+	again := order("charles_leclerc", "FORZAFERRARI")
+	second := order("carlos_sainz", "FORZAFERRARI")
+	exhausted := order("lewis_hamilton", "FORZAFERRARI")
+	expired := order("lewis_hamilton", "MONZA")
+	unknown := order("lewis_hamilton", "MCLAREN")
+
+	// Assert
+	require.Equal(t, http.StatusOK, first.Code)
+	var resp NewPizzaOrderResponse
+	require.NoError(t, json.Unmarshal(first.Body.Bytes(), &resp))
+	assert.Equal(t, int64(300), resp.Discount)
+	assert.Equal(t, int64(1200), resp.Total)
+
+	placed, err := pubSubber.GetOrder(t.Context(), resp.OrderID)
+	require.NoError(t, err)
+	assert.Equal(t, "FORZAFERRARI", placed.PromoCode)
+	assert.Equal(t, int64(1500), placed.Subtotal)
+	assert.Equal(t, int64(300), placed.Discount)
+
+	assert.Equal(t, http.StatusOK, second.Code)
+	for rec, rule := range map[*httptest.ResponseRecorder]string{
+		again:     "promo_user_limit",
+		exhausted: "promo_cap",
+		expired:   "promo_window",
+		unknown:   "promo",
+	} {
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		var problem ProblemDetails
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+		require.Len(t, problem.Errors, 1)
+		assert.Equal(t, FieldError{Field: "promo_code", Rule: rule, Message: problem.Errors[0].Message}, problem.Errors[0])
+	}
+}
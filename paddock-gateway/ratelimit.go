@@ -0,0 +1,366 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+	HeaderRateLimitPolicy    = "RateLimit-Policy"
+)
+
+func (q RateLimitQuota) period() time.Duration {
+	return time.Duration(q.PeriodInSeconds) * time.Second
+}
+
+// refillRate is the number of tokens added per second.
+func (q RateLimitQuota) refillRate() float64 {
+	return float64(q.Limit) / float64(q.PeriodInSeconds)
+}
+
+// RateLimitResult is the outcome of taking a token from a bucket.
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until the next token, only set when not allowed.
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps the token buckets.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, quota RateLimitQuota, now time.Time) (RateLimitResult, error)
+	// Refund gives back a token taken for a request that was rejected anyway.
+	Refund(ctx context.Context, key string, quota RateLimitQuota, now time.Time) error
+}
+
+type tokenBucket struct {
+	Tokens    float64   `json:"tokens"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// refill adds the tokens for the time elapsed since its last update. A zero
+// bucket starts full.
+func (b tokenBucket) refill(quota RateLimitQuota, now time.Time) tokenBucket {
+	capacity := float64(quota.Limit)
+
+	if b.UpdatedAt.IsZero() {
+		b.Tokens = capacity
+	} else if elapsed := now.Sub(b.UpdatedAt).Seconds(); elapsed > 0 {
+		b.Tokens = math.Min(capacity, b.Tokens+elapsed*quota.refillRate())
+	}
+	b.UpdatedAt = now
+
+	return b
+}
+
+// take refills the bucket and takes one token when available.
+func (b tokenBucket) take(quota RateLimitQuota, now time.Time) (tokenBucket, RateLimitResult) {
+	capacity := float64(quota.Limit)
+	rate := quota.refillRate()
+
+	b = b.refill(quota, now)
+
+	var result RateLimitResult
+	if b.Tokens >= 1 {
+		b.Tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - b.Tokens) / rate)
+	}
+
+	result.Remaining = int(b.Tokens)
+	result.Reset = secondsToDuration((capacity - b.Tokens) / rate)
+
+	return b, result
+}
+
+// refund refills the bucket and gives one token back, up to the limit.
+func (b tokenBucket) refund(quota RateLimitQuota, now time.Time) tokenBucket {
+	b = b.refill(quota, now)
+	b.Tokens = math.Min(float64(quota.Limit), b.Tokens+1)
+	return b
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// MemoryRateLimitStore keeps the buckets of a single gateway process.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]tokenBucket
+	lastSweep time.Time
+}
+
+var _ RateLimitStore = (*MemoryRateLimitStore)(nil)
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]tokenBucket)}
+}
+
+// Take implements RateLimitStore.
+func (m *MemoryRateLimitStore) Take(ctx context.Context, key string, quota RateLimitQuota, now time.Time) (RateLimitResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Buckets untouched for a whole period are full again, forgetting them is
+	// the same as keeping them
+	if now.Sub(m.lastSweep) > quota.period() {
+		for k, b := range m.buckets {
+			if now.Sub(b.UpdatedAt) > quota.period() {
+				delete(m.buckets, k)
+			}
+		}
+		m.lastSweep = now
+	}
+
+	bucket, result := m.buckets[key].take(quota, now)
+	m.buckets[key] = bucket
+
+	return result, nil
+}
+
+// Refund implements RateLimitStore.
+func (m *MemoryRateLimitStore) Refund(ctx context.Context, key string, quota RateLimitQuota, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// A forgotten bucket is full already
+	bucket, ok := m.buckets[key]
+	if ok {
+		m.buckets[key] = bucket.refund(quota, now)
+	}
+
+	return nil
+}
+
+// NATSRateLimitStore keeps the buckets in a key-value bucket so every gateway
+// replica shares the same limits. Buckets are updated with optimistic
+// concurrency on the key revision.
+type NATSRateLimitStore struct {
+	kv jetstream.KeyValue
+}
+
+var _ RateLimitStore = (*NATSRateLimitStore)(nil)
+
+const rateLimitMaxAttempts = 5
+
+// NewNATSRateLimitStore creates the key-value bucket. Keys expire after ttl,
+// which should be the longest quota period: an idle bucket is full by then.
+func NewNATSRateLimitStore(nc *nats.Conn, bucket string, ttl time.Duration) (*NATSRateLimitStore, error) {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		slog.Error("failed to create jetstream context", "error", err)
+		return nil, err
+	}
+
+	kv, err := js.CreateOrUpdateKeyValue(context.Background(), jetstream.KeyValueConfig{
+		Bucket:      bucket,
+		Description: "Rate limit token buckets shared by the gateway replicas",
+		TTL:         ttl,
+	})
+	if err != nil {
+		slog.Error("failed to create rate limit bucket", "error", err)
+		return nil, err
+	}
+
+	return &NATSRateLimitStore{kv: kv}, nil
+}
+
+// Take implements RateLimitStore.
+func (n *NATSRateLimitStore) Take(ctx context.Context, key string, quota RateLimitQuota, now time.Time) (RateLimitResult, error) {
+	ctx, span := tracer.Start(ctx, "NATSRateLimitStore.Take")
+	defer span.End()
+
+	var result RateLimitResult
+	err := n.update(ctx, key, func(bucket tokenBucket) tokenBucket {
+		bucket, result = bucket.take(quota, now)
+		return bucket
+	})
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+
+	return result, nil
+}
+
+// Refund implements RateLimitStore.
+func (n *NATSRateLimitStore) Refund(ctx context.Context, key string, quota RateLimitQuota, now time.Time) error {
+	ctx, span := tracer.Start(ctx, "NATSRateLimitStore.Refund")
+	defer span.End()
+
+	return n.update(ctx, key, func(bucket tokenBucket) tokenBucket {
+		return bucket.refund(quota, now)
+	})
+}
+
+// update applies change to the bucket of key, reading it again when another
+// replica updated it meanwhile.
+func (n *NATSRateLimitStore) update(ctx context.Context, key string, change func(tokenBucket) tokenBucket) error {
+	span := trace.SpanFromContext(ctx)
+
+	key = bucketKey(key)
+	for range rateLimitMaxAttempts {
+		var bucket tokenBucket
+		var revision uint64
+
+		entry, err := n.kv.Get(ctx, key)
+		switch {
+		case errors.Is(err, jetstream.ErrKeyNotFound):
+		case err != nil:
+			return err
+		default:
+			revision = entry.Revision()
+			err = json.Unmarshal(entry.Value(), &bucket)
+			if err != nil {
+				return err
+			}
+		}
+
+		data, err := json.Marshal(change(bucket))
+		if err != nil {
+			return err
+		}
+
+		if revision == 0 {
+			_, err = n.kv.Create(ctx, key, data)
+		} else {
+			_, err = n.kv.Update(ctx, key, data, revision)
+		}
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, jetstream.ErrKeyExists) {
+			return err
+		}
+
+		// Another replica changed the bucket meanwhile, read it again
+		span.AddEvent("rate limit bucket update conflict")
+	}
+
+	return fmt.Errorf("rate limit bucket kept changing after %d attempts", rateLimitMaxAttempts)
+}
+
+// newRateLimitMiddleware takes a token from the client IP bucket and, for
+// authenticated requests, from the username bucket. The request is rejected
+// with 429 when either is empty, and the RateLimit headers describe the most
+// restrictive of the two. A request rejected by the username bucket gets its
+// IP token back, so one tifoso over the limit does not use up the IP of the
+// whole garage. The username in the body is chosen by the client, it is never
+// used as a key: without authentication only the IP bucket applies.
+//
+// When the store fails the request goes through: an unavailable limiter must
+// not stop orders.
+func newRateLimitMiddleware(store RateLimitStore, settings RateLimitSettings) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := c.Request().Context()
+			now := time.Now()
+
+			type check struct {
+				key   string
+				quota RateLimitQuota
+			}
+			checks := []check{{key: "ip:" + c.RealIP(), quota: settings.PerIP}}
+			username, _ := authenticatedUser(c)
+			if username != "" {
+				checks = append(checks, check{key: "user:" + username, quota: settings.PerUser})
+			}
+
+			var limiting *RateLimitResult
+			var limitingQuota RateLimitQuota
+			var taken []check
+			for _, check := range checks {
+				result, err := store.Take(ctx, check.key, check.quota, now)
+				if err != nil {
+					slog.ErrorContext(ctx, "failed to check rate limit, letting the request through", slog.String("error", err.Error()))
+					continue
+				}
+				if result.Allowed {
+					taken = append(taken, check)
+				}
+
+				if limiting == nil || !result.Allowed || (limiting.Allowed && result.Remaining < limiting.Remaining) {
+					limiting = &result
+					limitingQuota = check.quota
+				}
+				if !result.Allowed {
+					break
+				}
+			}
+
+			if limiting == nil {
+				return next(c)
+			}
+
+			header := c.Response().Header()
+			header.Set(HeaderRateLimitLimit, strconv.Itoa(limitingQuota.Limit))
+			header.Set(HeaderRateLimitRemaining, strconv.Itoa(limiting.Remaining))
+			header.Set(HeaderRateLimitReset, strconv.Itoa(ceilSeconds(limiting.Reset)))
+			header.Set(HeaderRateLimitPolicy, fmt.Sprintf("%d;w=%d", limitingQuota.Limit, limitingQuota.PeriodInSeconds))
+
+			if !limiting.Allowed {
+				for _, check := range taken {
+					err := store.Refund(ctx, check.key, check.quota, now)
+					if err != nil {
+						slog.ErrorContext(ctx, "failed to refund rate limit token", slog.String("error", err.Error()))
+					}
+				}
+
+				slog.InfoContext(ctx, "rate limited request", slog.String("ip", c.RealIP()), slog.String("username", username))
+				header.Set(echo.HeaderRetryAfter, strconv.Itoa(ceilSeconds(limiting.RetryAfter)))
+				return writeProblem(c, newProblem(c, http.StatusTooManyRequests, "Too many requests, slow down"))
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// newIPExtractor is how c.RealIP finds the client IP, which keys the per-IP
+// buckets. X-Forwarded-For is set by the client unless the request comes from
+// one of the trusted proxies, so without any the peer address is used.
+func newIPExtractor(trustedProxies []string) (echo.IPExtractor, error) {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+
+	// Echo trusts every private network by default, which is any container
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, proxy := range trustedProxies {
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		options = append(options, echo.TrustIPRange(ipNet))
+	}
+
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenBucketTake(t *testing.T) {
+	quota := RateLimitQuota{Limit: 2, PeriodInSeconds: 10}
+	start := time.Date(2026, 5, 24, 15, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		bucket        tokenBucket
+		now           time.Time
+		wantAllowed   bool
+		wantRemaining int
+		wantRetry     time.Duration
+	}{
+		{
+			name:          "new bucket starts full",
+			now:           start,
+			wantAllowed:   true,
+			wantRemaining: 1,
+		},
+		{
+			name:        "empty bucket rejects until the next token",
+			bucket:      tokenBucket{Tokens: 0, UpdatedAt: start},
+			now:         start.Add(time.Second),
+			wantAllowed: false,
+			wantRetry:   4 * time.Second,
+		},
+		{
+			name:          "empty bucket refills over time",
+			bucket:        tokenBucket{Tokens: 0, UpdatedAt: start},
+			now:           start.Add(5 * time.Second),
+			wantAllowed:   true,
+			wantRemaining: 0,
+		},
+		{
+			name:          "refill stops at the limit",
+			bucket:        tokenBucket{Tokens: 0, UpdatedAt: start},
+			now:           start.Add(time.Hour),
+			wantAllowed:   true,
+			wantRemaining: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			_, result := tt.bucket.take(quota, tt.now)
+
+			// Assert
+			assert.Equal(t, tt.wantAllowed, result.Allowed)
+			assert.Equal(t, tt.wantRemaining, result.Remaining)
+			assert.Equal(t, tt.wantRetry, result.RetryAfter)
+		})
+	}
+}
+
+func TestTokenBucketRefund(t *testing.T) {
+	quota := RateLimitQuota{Limit: 2, PeriodInSeconds: 10}
+	start := time.Date(2026, 5, 24, 15, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		bucket     tokenBucket
+		wantTokens float64
+	}{
+		{
+			name:       "token given back",
+			bucket:     tokenBucket{Tokens: 0, UpdatedAt: start},
+			wantTokens: 1,
+		},
+		{
+			name:       "refund stops at the limit",
+			bucket:     tokenBucket{Tokens: 2, UpdatedAt: start},
+			wantTokens: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			bucket := tt.bucket.refund(quota, start)
+
+			// Assert
+			assert.Equal(t, tt.wantTokens, bucket.Tokens)
+		})
+	}
+}
+
+func TestRateLimitOrderNewPizza(t *testing.T) {
+	// Arrange
+	e := echo.New()
+	var err error
+	e.IPExtractor, err = newIPExtractor(nil)
+	require.NoError(t, err)
+
+	settings := &Settings{RateLimit: RateLimitSettings{
+		PerUser: RateLimitQuota{Limit: 2, PeriodInSeconds: 60},
+		PerIP:   RateLimitQuota{Limit: 3, PeriodInSeconds: 60},
+	}}
+	// Stands in for the JWT middleware, the username comes from a header
+	auth := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if username := c.Request().Header.Get("X-Test-Username"); username != "" {
+				c.Set(ContextKeyUserID, username)
+			}
+			return next(c)
+		}
+	}
+	pubSubber := NewGoChannelOrderPubSubber()
+	NewMainHandler(e, settings, MainHandlerDeps{OrderPubSubber: pubSubber, OrderGetter: pubSubber, Auth: auth, RateLimitStore: NewMemoryRateLimitStore()})
+
+	order := func(remoteAddr, authenticated, bodyUsername, forwardedFor string) *httptest.ResponseRecorder {
+		body := `{"size":"large","toppings":["pepperoni"],"destination":"Garage #16","username":"` + bodyUsername + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/v1/order", strings.NewReader(body))
+		req.RemoteAddr = remoteAddr
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set("X-Test-Username", authenticated)
+		req.Header.Set(echo.HeaderXForwardedFor, forwardedFor)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Act
+	first := order("192.0.2.1:1000", "charles_leclerc", "", "")
+	second := order("192.0.2.2:1000", "charles_leclerc", "", "")
+	limited := order("192.0.2.3:1000", "charles_leclerc", "", "")
+	limitedIP := order("192.0.2.3:1000", "", "charles_leclerc", "")
+	otherUser := order("192.0.2.4:1000", "carlos_sainz", "", "")
+
+	var anonymous []*httptest.ResponseRecorder
+	for i := range 4 {
+		// Neither the body username nor X-Forwarded-For can be trusted
+		anonymous = append(anonymous, order("198.51.100.1:1000", "", "tifoso_"+strconv.Itoa(i), "203.0.113."+strconv.Itoa(i)))
+	}
+
+	// Assert
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "1", first.Header().Get(HeaderRateLimitRemaining))
+	assert.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, http.StatusTooManyRequests, limited.Code)
+	assert.Equal(t, "30", limited.Header().Get(echo.HeaderRetryAfter))
+	assert.Equal(t, "2;w=60", limited.Header().Get(HeaderRateLimitPolicy))
+	assert.Equal(t, http.StatusOK, limitedIP.Code)
+	assert.Equal(t, "2", limitedIP.Header().Get(HeaderRateLimitRemaining), "the rejected request gave its IP token back")
+	assert.Equal(t, http.StatusOK, otherUser.Code)
+
+	for _, rec := range anonymous[:3] {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, anonymous[3].Code)
+	assert.Equal(t, "3;w=60", anonymous[3].Header().Get(HeaderRateLimitPolicy))
+}
+
+func TestNewIPExtractor(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		remoteAddr     string
+		want           string
+	}{
+		{
+			name:       "no trusted proxy",
+			remoteAddr: "172.28.0.10:4321",
+			want:       "172.28.0.10",
+		},
+		{
+			name:           "request from the trusted proxy",
+			trustedProxies: []string{"172.28.0.10/32"},
+			remoteAddr:     "172.28.0.10:4321",
+			want:           "203.0.113.7",
+		},
+		{
+			name:           "request from another private address",
+			trustedProxies: []string{"172.28.0.10/32"},
+			remoteAddr:     "172.28.1.5:4321",
+			want:           "172.28.1.5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			extract, err := newIPExtractor(tt.trustedProxies)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/order", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set(echo.HeaderXForwardedFor, "198.51.100.9, 203.0.113.7")
+
+			// Act
+			got := extract(req)
+
+			// Assert
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
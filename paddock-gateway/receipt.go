@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+type ReceiptLine struct {
+	Description string `json:"description"`
+	Quantity    int    `json:"quantity"`
+	UnitPrice   int64  `json:"unit_price"`
+	Total       int64  `json:"total"`
+}
+
+// Receipt itemises what an order cost, with the prices it was placed with.
+type Receipt struct {
+	OrderID     string        `json:"order_id"`
+	Username    string        `json:"username"`
+	Destination string        `json:"destination"`
+	OrderedAt   time.Time     `json:"ordered_at"`
+	Status      string        `json:"status"`
+	Lines       []ReceiptLine `json:"lines"`
+	Subtotal    int64         `json:"subtotal"`
+	PromoCode   string        `json:"promo_code,omitempty"`
+	Discount    int64         `json:"discount,omitempty"`
+	Total       int64         `json:"total"`
+	Currency    string        `json:"currency"` // Prices are in minor units of the currency
+}
+
+func NewReceipt(order Order) Receipt {
+	receipt := Receipt{
+		OrderID:     order.OrderID,
+		Username:    order.Username,
+		Destination: order.Destination,
+		OrderedAt:   order.OrderedAt,
+		Status:      order.Status,
+		Lines:       make([]ReceiptLine, 0, len(order.Items)),
+		Subtotal:    order.Subtotal,
+		PromoCode:   order.PromoCode,
+		Discount:    order.Discount,
+		Total:       order.Total,
+		Currency:    order.Currency,
+	}
+
+	for _, item := range order.Items {
+		receipt.Lines = append(receipt.Lines, ReceiptLine{
+			Description: describeItem(item),
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+			Total:       item.Total,
+		})
+	}
+
+	// Orders priced before promo codes have no subtotal
+	if receipt.Subtotal == 0 {
+		receipt.Subtotal = receipt.Total + receipt.Discount
+	}
+
+	return receipt
+}
+
+// WriteText renders the receipt as plain text, one line per item. Discounted
+// orders show the subtotal and the discount before the total.
+func (r Receipt) WriteText(w io.Writer) error {
+	header := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(header, "Order\t%s\n", r.OrderID)
+	fmt.Fprintf(header, "Customer\t%s\n", r.Username)
+	fmt.Fprintf(header, "Destination\t%s\n", r.Destination)
+	fmt.Fprintf(header, "Ordered at\t%s\n", r.OrderedAt.Format(time.RFC3339))
+	fmt.Fprintf(header, "Status\t%s\n", r.Status)
+	err := header.Flush()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w)
+
+	descriptions := make([]string, 0, len(r.Lines))
+	width := len("Total ") + len(r.Currency)
+	discount := "Discount " + r.PromoCode
+	if r.Discount > 0 {
+		width = max(width, len(discount))
+	}
+	for _, line := range r.Lines {
+		description := fmt.Sprintf("%d x %s", line.Quantity, line.Description)
+		descriptions = append(descriptions, description)
+		width = max(width, len(description))
+	}
+
+	for i, line := range r.Lines {
+		fmt.Fprintf(w, "%-*s  %8s  %8s\n", width, descriptions[i], formatPrice(line.UnitPrice), formatPrice(line.Total))
+	}
+	if r.Discount > 0 {
+		fmt.Fprintf(w, "%-*s  %8s  %8s\n", width, "Subtotal", "", formatPrice(r.Subtotal))
+		fmt.Fprintf(w, "%-*s  %8s  %8s\n", width, discount, "", "-"+formatPrice(r.Discount))
+	}
+	_, err = fmt.Fprintf(w, "%-*s  %8s  %8s\n", width, "Total "+r.Currency, "", formatPrice(r.Total))
+
+	return err
+}
+
+// describeItem names a pizza as "large, cheddar border: pepperoni, basil".
+func describeItem(item OrderItem) string {
+	description := item.Size
+	if item.Border != "" && item.Border != BorderNone {
+		description += ", " + strings.ReplaceAll(item.Border, "_", " ") + " border"
+	}
+	return description + ": " + strings.Join(item.Toppings, ", ")
+}
@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"log"
 	"strings"
+	"time"
 
 	_ "embed"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/go-viper/mapstructure/v2"
 	"github.com/spf13/viper"
 	"github.com/taldoflemis/box-box/pacchetto"
 )
@@ -15,11 +17,93 @@ import (
 //go:embed base.yaml
 var baseConfig []byte
 
+type OutboxSettings struct {
+	Enabled                bool   `mapstructure:"enabled"`
+	Directory              string `mapstructure:"directory" validate:"required_if=Enabled true"`
+	RelayIntervalInSeconds int    `mapstructure:"relay-interval-in-seconds" validate:"required,min=1"`
+}
+
+type SSESettings struct {
+	BufferSize                    int    `mapstructure:"buffer-size" validate:"required,min=1"`
+	SlowConsumerPolicy            string `mapstructure:"slow-consumer-policy" validate:"required,oneof=drop disconnect coalesce"`
+	HeartbeatIntervalInSeconds    int    `mapstructure:"heartbeat-interval-in-seconds" validate:"required,min=1"`
+	ReconnectDelayInMilliseconds  int    `mapstructure:"reconnect-delay-in-milliseconds" validate:"required,min=1"`
+	ShutdownGraceTimeoutInSeconds int    `mapstructure:"shutdown-grace-timeout-in-seconds" validate:"required,min=1"`
+}
+
+type AuthSettings struct {
+	Enabled bool `mapstructure:"enabled"`
+	// JWKS is a file path or an http(s) URL serving the JSON Web Key Set
+	JWKS                     string `mapstructure:"jwks" validate:"required_if=Enabled true"`
+	Issuer                   string `mapstructure:"issuer"`
+	Audience                 string `mapstructure:"audience"`
+	RefreshIntervalInSeconds int    `mapstructure:"refresh-interval-in-seconds" validate:"required,min=1"`
+	LeewayInSeconds          int    `mapstructure:"leeway-in-seconds" validate:"min=0"`
+}
+
+// RateLimitQuota allows Limit requests per period, as a token bucket that holds
+// Limit tokens and refills completely in one period.
+type RateLimitQuota struct {
+	Limit           int `mapstructure:"limit" validate:"required,min=1"`
+	PeriodInSeconds int `mapstructure:"period-in-seconds" validate:"required,min=1"`
+}
+
+type RateLimitSettings struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Store is memory for limits per replica or nats to share them between replicas
+	Store  string `mapstructure:"store" validate:"required,oneof=memory nats"`
+	Bucket string `mapstructure:"bucket" validate:"required"`
+	// PerUser keys on the token subject, it never applies with authentication off
+	PerUser RateLimitQuota `mapstructure:"per-user" validate:"required"`
+	PerIP   RateLimitQuota `mapstructure:"per-ip" validate:"required"`
+}
+
+type MenuSettings struct {
+	// Path of a menu file to serve and reload on change, the embedded menu.yaml is used when empty
+	Path string `mapstructure:"path"`
+}
+
+// PricingSettings holds prices in minor units of Currency, cents for EUR.
+type PricingSettings struct {
+	Currency string           `mapstructure:"currency" validate:"required,len=3"`
+	Sizes    map[string]int64 `mapstructure:"sizes" validate:"required,min=1,dive,min=0"`
+	Borders  map[string]int64 `mapstructure:"borders" validate:"dive,min=0"`
+	// ToppingDefault is the price of every topping missing from Toppings
+	ToppingDefault int64            `mapstructure:"topping-default" validate:"min=0"`
+	Toppings       map[string]int64 `mapstructure:"toppings" validate:"dive,min=0"`
+}
+
+type PromotionsSettings struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Store is memory to count redemptions per replica, or nats to share them
+	// between replicas and serve the codes of CodesBucket too
+	Store             string      `mapstructure:"store" validate:"required,oneof=memory nats"`
+	CodesBucket       string      `mapstructure:"codes-bucket" validate:"required"`
+	RedemptionsBucket string      `mapstructure:"redemptions-bucket" validate:"required"`
+	Codes             []PromoCode `mapstructure:"codes" validate:"dive"`
+}
+
+type OrdersSettings struct {
+	IdempotencyKeyTTLInSeconds int            `mapstructure:"idempotency-key-ttl-in-seconds" validate:"required,min=1"`
+	DuplicateWindowInSeconds   int            `mapstructure:"duplicate-window-in-seconds" validate:"required,min=1"`
+	RetryAfterInSeconds        int            `mapstructure:"retry-after-in-seconds" validate:"required,min=1"`
+	Outbox                     OutboxSettings `mapstructure:"outbox" validate:"required"`
+}
+
 type Settings struct {
-	App           pacchetto.AppSettings           `mapstructure:"app" validate:"required"`
-	HTTP          pacchetto.HTTPSettings          `mapstructure:"http" validate:"required"`
-	Nats          pacchetto.NatsSettings          `mapstructure:"nats" validate:"required"`
-	OpenTelemetry pacchetto.OpenTelemetrySettings `mapstructure:"opentelemetry" validate:"required"`
+	App        pacchetto.AppSettings  `mapstructure:"app" validate:"required"`
+	HTTP       pacchetto.HTTPSettings `mapstructure:"http" validate:"required"`
+	Auth       AuthSettings           `mapstructure:"auth" validate:"required"`
+	Menu       MenuSettings           `mapstructure:"menu"`
+	Pricing    PricingSettings        `mapstructure:"pricing" validate:"required"`
+	Promotions PromotionsSettings     `mapstructure:"promotions" validate:"required"`
+	Orders     OrdersSettings         `mapstructure:"orders" validate:"required"`
+	RateLimit  RateLimitSettings      `mapstructure:"rate-limit" validate:"required"`
+	// TrustedProxies are the CIDRs allowed to set X-Forwarded-For
+	TrustedProxies []string                        `mapstructure:"trusted-proxies" validate:"dive,cidr"`
+	SSE            SSESettings                     `mapstructure:"sse" validate:"required"`
+	Nats           pacchetto.NatsSettings          `mapstructure:"nats" validate:"required"`
+	OpenTelemetry  pacchetto.OpenTelemetrySettings `mapstructure:"opentelemetry" validate:"required"`
 }
 
 func LoadConfig() (*Settings, error) {
@@ -36,14 +120,19 @@ func LoadConfig() (*Settings, error) {
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", ""))
 	viper.AutomaticEnv()
 
-	err = viper.Unmarshal(&cfg)
+	// Promo code validity windows are RFC 3339 timestamps
+	err = viper.Unmarshal(&cfg, viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+		mapstructure.StringToTimeHookFunc(time.RFC3339),
+	)))
 	if err != nil {
 		return nil, err
 	}
 
 	validate := validator.New()
 	allowedHeaders := map[string]struct{}{
-		"Accept": {}, "Authorization": {}, "Content-Type": {}, "X-CSRF-Token": {},
+		"Accept": {}, "Authorization": {}, "Content-Type": {}, "X-CSRF-Token": {}, "Idempotency-Key": {},
 	}
 	validate.RegisterValidation("baseheader", func(fl validator.FieldLevel) bool {
 		header := fl.Field().String()
@@ -1 +1,22 @@
 package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	// Act
+	settings, err := LoadConfig()
+
+	// Assert
+	require.NoError(t, err)
+	require.NotEmpty(t, settings.Promotions.Codes)
+	promo := settings.Promotions.Codes[0]
+	assert.Equal(t, "FORZAFERRARI", promo.Code)
+	assert.Equal(t, time.Date(2026, 5, 24, 14, 0, 0, 0, time.UTC), promo.ValidFrom.UTC())
+	assert.Equal(t, time.Date(2026, 5, 26, 0, 0, 0, 0, time.UTC), promo.ValidUntil.UTC())
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const HeaderLastEventID = "Last-Event-ID"
+
+// SSE event types, clients listen to them with EventSource.addEventListener.
+const (
+	// SSEEventOrder carries the latest state of an order.
+	SSEEventOrder = "order"
+	// SSEEventEnd is sent before closing a per-order stream whose order will not
+	// change anymore, so clients close instead of reconnecting.
+	SSEEventEnd = "end"
+	// SSEEventShutdown is sent to every subscriber when the gateway stops.
+	// Clients reconnect, to another replica, after the retry delay.
+	SSEEventShutdown = "shutdown"
+)
+
+var errInvalidSubscribeOptions = errors.New("invalid subscribe options")
+
+// parseSubscribeOptions reads where an SSE client wants its stream to start.
+//
+// A Last-Event-ID header, sent by browsers when reconnecting, always wins and
+// resumes right after that sequence. Otherwise the deliver query parameter
+// selects new (live only), all (whole history) or since, which replays from
+// the RFC 3339 timestamp in the since parameter. A since parameter alone
+// implies deliver=since.
+func parseSubscribeOptions(c echo.Context, defaultPolicy DeliverPolicy) (SubscribeOptions, error) {
+	lastEventID := c.Request().Header.Get(HeaderLastEventID)
+	if lastEventID != "" {
+		sequence, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			return SubscribeOptions{}, fmt.Errorf("%w: %s must be a stream sequence", errInvalidSubscribeOptions, HeaderLastEventID)
+		}
+
+		return SubscribeOptions{Deliver: DeliverByStartSequence, StartSequence: sequence + 1}, nil
+	}
+
+	deliver := c.QueryParam("deliver")
+	since := c.QueryParam("since")
+	if deliver == "" && since != "" {
+		deliver = "since"
+	}
+
+	switch deliver {
+	case "":
+		return SubscribeOptions{Deliver: defaultPolicy}, nil
+	case "new":
+		return SubscribeOptions{Deliver: DeliverNew}, nil
+	case "all":
+		return SubscribeOptions{Deliver: DeliverAll}, nil
+	case "since":
+		startTime, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return SubscribeOptions{}, fmt.Errorf("%w: since must be an RFC 3339 timestamp", errInvalidSubscribeOptions)
+		}
+
+		return SubscribeOptions{Deliver: DeliverByStartTime, StartTime: startTime}, nil
+	default:
+		return SubscribeOptions{}, fmt.Errorf("%w: deliver must be one of new, all, since", errInvalidSubscribeOptions)
+	}
+}
+
+// streamOrderEvents writes every event from ch as SSE until the client leaves,
+// the subscription is closed, the gateway drains or complete reports that the
+// stream is over. Idle streams get a ping comment every heartbeat interval so
+// proxies keep them open.
+func (h *MainHandler) streamOrderEvents(
+	c echo.Context,
+	flusher http.Flusher,
+	ch <-chan OrderEvent,
+	complete func(OrderEvent) bool,
+) error {
+	ctx := c.Request().Context()
+
+	if !h.beginStream() {
+		return h.unavailable(c, "The gateway is shutting down, reconnect to another replica")
+	}
+	defer h.streams.Done()
+
+	c.Response().Header().Set(echo.HeaderContentType, "text/event-stream")
+	c.Response().Header().Set("Cache-Control", "no-cache")
+	c.Response().Header().Set("Connection", "keep-alive")
+	c.Response().WriteHeader(http.StatusOK)
+
+	w := c.Response()
+	_, err := fmt.Fprintf(w, "retry: %d\n\n", h.reconnectDelay.Milliseconds())
+	if err != nil {
+		slog.ErrorContext(ctx, "write SSE", slog.String("error", err.Error()))
+		return err
+	}
+	flusher.Flush()
+
+	var heartbeat <-chan time.Time
+	if h.heartbeatInterval > 0 {
+		ticker := time.NewTicker(h.heartbeatInterval)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "client closed connection")
+			return nil
+		case <-h.draining:
+			slog.InfoContext(ctx, "gateway is shutting down, closing stream")
+			err = writeSSEMessage(w, "", SSEEventShutdown, []byte("{}"))
+			flusher.Flush()
+			return err
+		case <-heartbeat:
+			_, err = io.WriteString(w, ": ping\n\n")
+		case event, ok := <-ch:
+			if !ok {
+				slog.WarnContext(ctx, "subscription closed by the server")
+				return nil
+			}
+
+			err = writeSSEEvent(w, event)
+			if err == nil && complete(event) {
+				slog.InfoContext(ctx, "stream is complete, closing it", slog.String("order_id", event.Order.OrderID), slog.String("status", event.Order.Status))
+				err = writeSSEMessage(w, "", SSEEventEnd, []byte("{}"))
+				flusher.Flush()
+				return err
+			}
+		}
+
+		if err != nil {
+			slog.ErrorContext(ctx, "write SSE", slog.String("error", err.Error()))
+			return err
+		}
+		flusher.Flush()
+	}
+}
+
+// beginStream registers a long-lived stream so Drain waits for it. It reports
+// false once the gateway is draining, otherwise the caller must call
+// h.streams.Done when the stream ends.
+func (h *MainHandler) beginStream() bool {
+	h.drainMu.Lock()
+	defer h.drainMu.Unlock()
+
+	select {
+	case <-h.draining:
+		return false
+	default:
+	}
+
+	h.streams.Add(1)
+	return true
+}
+
+// Drain tells every open stream to send a shutdown event and close, then waits
+// for them until ctx is done. New streams are refused from then on.
+func (h *MainHandler) Drain(ctx context.Context) {
+	h.drainMu.Lock()
+	select {
+	case <-h.draining:
+	default:
+		close(h.draining)
+	}
+	h.drainMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		h.streams.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		slog.InfoContext(ctx, "all streams drained")
+	case <-ctx.Done():
+		slog.WarnContext(ctx, "shutdown grace timeout reached before all streams drained")
+	}
+}
+
+// writeSSEEvent writes event as an order message whose id is the event sequence.
+func writeSSEEvent(w io.Writer, event OrderEvent) error {
+	data, err := json.Marshal(event.Order)
+	if err != nil {
+		return err
+	}
+
+	// The current status read from the bucket has no position in the stream
+	id := ""
+	if event.Sequence != 0 {
+		id = strconv.FormatUint(event.Sequence, 10)
+	}
+
+	return writeSSEMessage(w, id, SSEEventOrder, data)
+}
+
+func writeSSEMessage(w io.Writer, id, eventType string, data []byte) error {
+	var err error
+	if id != "" {
+		_, err = fmt.Fprintf(w, "id: %s\n", id)
+	}
+	if err == nil {
+		_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", eventType, data)
+	}
+	return err
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSubscribeOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		lastEventID string
+		want        SubscribeOptions
+		wantErr     bool
+	}{
+		{
+			name: "defaults to the handler policy",
+			want: SubscribeOptions{Deliver: DeliverAll},
+		},
+		{
+			name:  "live only",
+			query: "deliver=new",
+			want:  SubscribeOptions{Deliver: DeliverNew},
+		},
+		{
+			name:  "since implies deliver by start time",
+			query: "since=2025-09-15T10:30:00Z",
+			want:  SubscribeOptions{Deliver: DeliverByStartTime, StartTime: time.Date(2025, 9, 15, 10, 30, 0, 0, time.UTC)},
+		},
+		{
+			name:        "last event id wins over the query",
+			query:       "deliver=new",
+			lastEventID: "41",
+			want:        SubscribeOptions{Deliver: DeliverByStartSequence, StartSequence: 42},
+		},
+		{
+			name:    "since without a timestamp",
+			query:   "deliver=since",
+			wantErr: true,
+		},
+		{
+			name:    "unknown policy",
+			query:   "deliver=last",
+			wantErr: true,
+		},
+		{
+			name:        "last event id that is not a sequence",
+			lastEventID: "abc",
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/v1/order/sse?"+tt.query, nil)
+			if tt.lastEventID != "" {
+				req.Header.Set(HeaderLastEventID, tt.lastEventID)
+			}
+			c := e.NewContext(req, httptest.NewRecorder())
+
+			// Act
+			opts, err := parseSubscribeOptions(c, DeliverAll)
+
+			// Assert
+			if tt.wantErr {
+				assert.ErrorIs(t, err, errInvalidSubscribeOptions)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, opts)
+		})
+	}
+}
+
+func TestLiveOrdersSSEDrain(t *testing.T) {
+	// Arrange
+	e := echo.New()
+	settings := &Settings{SSE: SSESettings{ReconnectDelayInMilliseconds: 1500}}
+	pubSubber := NewGoChannelOrderPubSubber()
+	handler := NewMainHandler(e, settings, MainHandlerDeps{OrderPubSubber: pubSubber, OrderGetter: pubSubber})
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/v1/order/sse")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+
+	readMessage := func() string {
+		var lines []string
+		for {
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err)
+			if line == "\n" {
+				return strings.Join(lines, "")
+			}
+			lines = append(lines, line)
+		}
+	}
+	retry := readMessage()
+
+	// Act
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	handler.Drain(ctx)
+	shutdown := readMessage()
+
+	// Assert
+	assert.Equal(t, "text/event-stream", resp.Header.Get(echo.HeaderContentType))
+	assert.Equal(t, "retry: 1500\n", retry)
+	assert.Equal(t, "event: shutdown\ndata: {}\n", shutdown)
+	assert.NoError(t, ctx.Err(), "drain should not wait for the grace timeout")
+}
+
+func TestOrderSSETerminalOrder(t *testing.T) {
+	// Arrange
+	e := echo.New()
+	pubSubber := NewGoChannelOrderPubSubber()
+	NewMainHandler(e, &Settings{SSE: SSESettings{ReconnectDelayInMilliseconds: 1500}}, MainHandlerDeps{OrderPubSubber: pubSubber, OrderGetter: pubSubber})
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	err := pubSubber.PubOrder(context.Background(), Order{OrderID: "order-1", Status: OrderStatusCancelled})
+	require.NoError(t, err)
+
+	client := &http.Client{Timeout: time.Second}
+
+	// Act
+	resp, err := client.Get(server.URL + "/v1/order/order-1/sse?deliver=new")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+
+	// Assert
+	require.NoError(t, err, "the stream of a terminal order must close")
+	assert.Contains(t, string(body), "event: order\ndata: {")
+	assert.Contains(t, string(body), `"status":"cancelled"`)
+	assert.NotContains(t, string(body), "id: ")
+	assert.True(t, strings.HasSuffix(string(body), "event: end\ndata: {}\n\n"))
+}
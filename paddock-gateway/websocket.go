@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/net/websocket"
+)
+
+// Commands a kitchen dashboard can send over the WebSocket.
+const (
+	WSCommandSubscribe   = "subscribe"
+	WSCommandUnsubscribe = "unsubscribe"
+)
+
+// Messages the gateway sends over the WebSocket.
+const (
+	WSMessageOrder        = "order"
+	WSMessageSubscribed   = "subscribed"
+	WSMessageUnsubscribed = "unsubscribed"
+	WSMessagePing         = "ping"
+	WSMessageError        = "error"
+	WSMessageShutdown     = "shutdown"
+)
+
+// WSFilter narrows the orders sent to a dashboard. Empty lists match everything.
+type WSFilter struct {
+	Statuses  []string `json:"statuses,omitempty"`
+	Usernames []string `json:"usernames,omitempty"`
+}
+
+func (f WSFilter) Matches(order Order) bool {
+	if len(f.Statuses) > 0 && !slices.Contains(f.Statuses, order.Status) {
+		return false
+	}
+	if len(f.Usernames) > 0 && !slices.Contains(f.Usernames, order.Username) {
+		return false
+	}
+	return true
+}
+
+type WSCommand struct {
+	Type   string   `json:"type"`
+	Filter WSFilter `json:"filter"`
+}
+
+type WSMessage struct {
+	Type     string    `json:"type"`
+	Sequence uint64    `json:"sequence,omitempty"`
+	Order    *Order    `json:"order,omitempty"`
+	Filter   *WSFilter `json:"filter,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// kitchenSession is the state of one WebSocket connection. It is only touched
+// by the goroutine running serve.
+type kitchenSession struct {
+	h      *MainHandler
+	ws     *websocket.Conn
+	key    http.Flusher
+	events <-chan OrderEvent
+	filter WSFilter
+}
+
+// KitchenWebSocket godoc
+//
+// @Summary Bidirectional order feed for kitchen dashboards over WebSocket
+// @Description Send {"type":"subscribe","filter":{"statuses":["waiting_to_cook"],"usernames":["charles_leclerc"]}}
+// @Description to receive live orders as {"type":"order","sequence":42,"order":{...}} and {"type":"unsubscribe"} to stop.
+// @Description Sending subscribe again replaces the filter.
+// @Tags order
+// @Security Bearer
+// @Success 101
+// @Failure 401 {object} ProblemDetails
+// @Router /v1/ws [get]
+func (h *MainHandler) KitchenWebSocket(c echo.Context) error {
+	// Without a Handshake func the origin is not checked, every origin may
+	// watch orders like on the SSE endpoints.
+	server := websocket.Server{
+		Handler: func(ws *websocket.Conn) {
+			session := &kitchenSession{h: h, ws: ws, key: c.Response()}
+			session.serve(c.Request().Context())
+		},
+	}
+	server.ServeHTTP(c.Response(), c.Request())
+
+	return nil
+}
+
+func (s *kitchenSession) serve(ctx context.Context) {
+	defer s.ws.Close()
+
+	if !s.h.beginStream() {
+		s.send(ctx, WSMessage{Type: WSMessageShutdown})
+		return
+	}
+	defer s.h.streams.Done()
+	defer s.unsubscribe(ctx)
+
+	done := make(chan struct{})
+	defer close(done)
+
+	commands := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		for {
+			var data []byte
+			err := websocket.Message.Receive(s.ws, &data)
+			if err != nil {
+				readErr <- err
+				return
+			}
+
+			select {
+			case commands <- data:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var heartbeat <-chan time.Time
+	if s.h.heartbeatInterval > 0 {
+		ticker := time.NewTicker(s.h.heartbeatInterval)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.h.draining:
+			slog.InfoContext(ctx, "gateway is shutting down, closing WebSocket")
+			s.send(ctx, WSMessage{Type: WSMessageShutdown})
+			return
+		case err = <-readErr:
+			if errors.Is(err, io.EOF) {
+				slog.InfoContext(ctx, "client closed WebSocket")
+				return
+			}
+		case <-heartbeat:
+			err = s.send(ctx, WSMessage{Type: WSMessagePing})
+		case data := <-commands:
+			err = s.handleCommand(ctx, data)
+		case event, ok := <-s.events:
+			if !ok {
+				slog.WarnContext(ctx, "subscription closed by the server")
+				s.unsubscribe(ctx)
+				err = s.send(ctx, WSMessage{Type: WSMessageError, Error: "subscription closed, subscribe again"})
+				break
+			}
+			if s.filter.Matches(event.Order) {
+				err = s.send(ctx, WSMessage{Type: WSMessageOrder, Sequence: event.Sequence, Order: &event.Order})
+			}
+		}
+
+		if err != nil {
+			slog.ErrorContext(ctx, "WebSocket connection failed", slog.String("error", err.Error()))
+			return
+		}
+	}
+}
+
+func (s *kitchenSession) handleCommand(ctx context.Context, data []byte) error {
+	var cmd WSCommand
+	err := json.Unmarshal(data, &cmd)
+	if err != nil {
+		return s.send(ctx, WSMessage{Type: WSMessageError, Error: "commands must be JSON objects"})
+	}
+
+	ctx, span := tracer.Start(ctx, "MainHandler.KitchenCommand", trace.WithAttributes(attribute.String("command", cmd.Type)))
+	defer span.End()
+
+	switch cmd.Type {
+	case WSCommandSubscribe:
+		if s.events == nil {
+			s.events, err = s.h.orderPubSubber.SubLiveOrders(ctx, s.key, SubscribeOptions{Deliver: DeliverNew})
+			if err != nil {
+				slog.ErrorContext(ctx, "failed to subscribe to live orders", slog.String("error", err.Error()))
+				span.SetStatus(codes.Error, "failed to subscribe to live orders")
+				span.RecordError(err)
+				return s.send(ctx, WSMessage{Type: WSMessageError, Error: "failed to subscribe, try again later"})
+			}
+		}
+		s.filter = cmd.Filter
+		slog.InfoContext(ctx, "kitchen subscribed to orders", slog.Any("filter", s.filter))
+		return s.send(ctx, WSMessage{Type: WSMessageSubscribed, Filter: &s.filter})
+	case WSCommandUnsubscribe:
+		s.unsubscribe(ctx)
+		return s.send(ctx, WSMessage{Type: WSMessageUnsubscribed})
+	default:
+		return s.send(ctx, WSMessage{Type: WSMessageError, Error: "unknown command " + cmd.Type})
+	}
+}
+
+func (s *kitchenSession) unsubscribe(ctx context.Context) {
+	if s.events == nil {
+		return
+	}
+
+	s.events = nil
+	err := s.h.orderPubSubber.UnsubLiveOrders(ctx, s.key)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to unsubscribe from live orders", slog.String("error", err.Error()))
+	}
+}
+
+func (s *kitchenSession) send(ctx context.Context, msg WSMessage) error {
+	err := websocket.JSON.Send(s.ws, msg)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to send WebSocket message", slog.String("type", msg.Type), slog.String("error", err.Error()))
+	}
+	return err
+}
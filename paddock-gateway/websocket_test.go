@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+func TestKitchenWebSocketFilters(t *testing.T) {
+	// Arrange
+	e := echo.New()
+	pubSubber := NewGoChannelOrderPubSubber()
+	NewMainHandler(e, &Settings{}, MainHandlerDeps{OrderPubSubber: pubSubber, OrderGetter: pubSubber})
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/v1/ws"
+	ws, err := websocket.Dial(url, "", server.URL)
+	require.NoError(t, err)
+	defer ws.Close()
+
+	var subscribed, order WSMessage
+	require.NoError(t, websocket.JSON.Send(ws, WSCommand{
+		Type:   WSCommandSubscribe,
+		Filter: WSFilter{Usernames: []string{"charles_leclerc"}},
+	}))
+	require.NoError(t, websocket.JSON.Receive(ws, &subscribed))
+
+	// Act
+	ctx := context.Background()
+	require.NoError(t, pubSubber.PubOrder(ctx, Order{OrderID: "1", Username: "carlos_sainz"}))
+	require.NoError(t, pubSubber.PubOrder(ctx, Order{OrderID: "2", Username: "charles_leclerc"}))
+	require.NoError(t, websocket.JSON.Receive(ws, &order))
+
+	// Assert
+	assert.Equal(t, WSMessageSubscribed, subscribed.Type)
+	assert.Equal(t, WSMessageOrder, order.Type)
+	require.NotNil(t, order.Order)
+	assert.Equal(t, "2", order.Order.OrderID)
+}
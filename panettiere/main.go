@@ -23,6 +23,7 @@ import (
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/health"
 	healthgrpc "google.golang.org/grpc/health/grpc_health_v1"
@@ -30,6 +31,7 @@ import (
 	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 var tracer = otel.Tracer("panettiere")
@@ -154,8 +156,15 @@ type panettiereService struct {
 	isWorkingOnDough bool
 	sleepTicker      *time.Ticker
 	shouldSleep      bool
-	ctx              context.Context
-	cancel           context.CancelFunc
+	// state, orderID, since and nextSleep are published to the WatchStatus
+	// streams, guarded by mu as the fields above
+	state     panettierev1pb.State
+	orderID   string
+	since     time.Time
+	nextSleep time.Time
+	watchers  *pacchetto.StatusWatchers[*panettierev1pb.WatchStatusResponse]
+	ctx       context.Context
+	cancel    context.CancelFunc
 }
 
 func newPanettiereService(panettiereSettings PanettiereSettings) *panettiereService {
@@ -167,9 +176,13 @@ func newPanettiereService(panettiereSettings PanettiereSettings) *panettiereServ
 	service := &panettiereService{
 		settings: panettiereSettings,
 		status:   "idle",
+		state:    panettierev1pb.State_Idle,
+		since:    time.Now(),
 		ctx:      ctx,
 		cancel:   cancel,
 	}
+	service.nextSleep = service.since.Add(time.Duration(panettiereSettings.PeriodBetweenSleepInSeconds) * time.Second)
+	service.watchers = pacchetto.NewStatusWatchers(service.watchStatusResponse())
 
 	// Start the sleep ticker
 	service.startSleepTicker()
@@ -186,6 +199,7 @@ func (p *panettiereService) startSleepTicker() {
 			select {
 			case <-p.sleepTicker.C:
 				p.mu.Lock()
+				p.nextSleep = time.Now().Add(duration)
 				if !p.isWorkingOnDough && !p.isSleeping {
 					// Go to sleep immediately if not working
 					p.isSleeping = true
@@ -206,12 +220,14 @@ func (p *panettiereService) startSleepTicker() {
 					}
 
 					p.status = "sleeping"
+					p.setState(panettierev1pb.State_Sleeping, "")
 
 					go func() {
 						time.Sleep(sleepDuration)
 						p.mu.Lock()
 						p.isSleeping = false
 						p.status = "idle"
+						p.setState(panettierev1pb.State_Idle, "")
 						p.mu.Unlock()
 						slog.InfoContext(p.ctx, "Panettiere woke up and is ready to work")
 					}()
@@ -219,6 +235,10 @@ func (p *panettiereService) startSleepTicker() {
 					// Mark that sleep should happen after work is done
 					p.shouldSleep = true
 					slog.InfoContext(p.ctx, "Sleep timer triggered, panettiere should sleep after current work")
+					p.publishStatus()
+				} else {
+					// Already sleeping, only the next sleep changed
+					p.publishStatus()
 				}
 				p.mu.Unlock()
 			case <-p.ctx.Done():
@@ -252,18 +272,47 @@ func (p *panettiereService) checkAndSleep() {
 		}
 
 		p.status = "sleeping"
+		p.setState(panettierev1pb.State_Sleeping, "")
 
 		go func() {
 			time.Sleep(sleepDuration)
 			p.mu.Lock()
 			p.isSleeping = false
 			p.status = "idle"
+			p.setState(panettierev1pb.State_Idle, "")
 			p.mu.Unlock()
 			slog.InfoContext(p.ctx, "Panettiere woke up and is ready to work")
 		}()
 	}
 }
 
+// setState must be called with mu held, it publishes the new state to the
+// WatchStatus streams.
+func (p *panettiereService) setState(state panettierev1pb.State, orderID string) {
+	if state != p.state || orderID != p.orderID {
+		p.since = time.Now()
+	}
+	p.state = state
+	p.orderID = orderID
+
+	p.publishStatus()
+}
+
+// publishStatus must be called with mu held, which keeps the statuses in order.
+func (p *panettiereService) publishStatus() {
+	p.watchers.Publish(p.watchStatusResponse())
+}
+
+func (p *panettiereService) watchStatusResponse() *panettierev1pb.WatchStatusResponse {
+	return &panettierev1pb.WatchStatusResponse{
+		State:          p.state,
+		OrderId:        p.orderID,
+		Since:          timestamppb.New(p.since),
+		NextSleep:      timestamppb.New(p.nextSleep),
+		SleepAfterWork: p.shouldSleep,
+	}
+}
+
 func (p *panettiereService) Stop() {
 	if p.sleepTicker != nil {
 		p.sleepTicker.Stop()
@@ -293,6 +342,7 @@ func (p *panettiereService) MakeDough(ctx context.Context, req *panettierev1pb.D
 	p.mu.Lock()
 	p.isWorkingOnDough = true
 	p.status = fmt.Sprintf("making dough of order %s", req.OrderId)
+	p.setState(panettierev1pb.State_MakingDough, req.OrderId)
 	p.mu.Unlock()
 
 	defer func() {
@@ -300,6 +350,7 @@ func (p *panettiereService) MakeDough(ctx context.Context, req *panettierev1pb.D
 		p.mu.Lock()
 		p.isWorkingOnDough = false
 		p.status = "idle"
+		p.setState(panettierev1pb.State_Idle, "")
 		p.mu.Unlock()
 
 		// Check if we should sleep after finishing the work
@@ -361,4 +412,27 @@ func (p *panettiereService) Status(context.Context, *emptypb.Empty) (*panettiere
 	}, nil
 }
 
+// WatchStatus implements v1.PanettiereServiceServer.
+func (p *panettiereService) WatchStatus(_ *emptypb.Empty, stream grpc.ServerStreamingServer[panettierev1pb.WatchStatusResponse]) error {
+	ctx, span := tracer.Start(stream.Context(), "panettiereService.WatchStatus")
+	defer span.End()
+
+	slog.DebugContext(ctx, "Started watching panettiere status")
+	defer slog.DebugContext(ctx, "Stopped watching panettiere status")
+
+	statuses := p.watchers.Watch(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case status := <-statuses:
+			err := stream.Send(status)
+			if err != nil {
+				span.RecordError(err)
+				return err
+			}
+		}
+	}
+}
+
 var _ panettierev1pb.PanettiereServiceServer = (*panettiereService)(nil)
@@ -10,6 +10,7 @@ import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -123,6 +124,55 @@ func (PizzaSize) EnumDescriptor() ([]byte, []int) {
 	return file_panettiere_v1_service_proto_rawDescGZIP(), []int{1}
 }
 
+type State int32
+
+const (
+	State_Idle        State = 0
+	State_MakingDough State = 1
+	State_Sleeping    State = 2
+)
+
+// Enum value maps for State.
+var (
+	State_name = map[int32]string{
+		0: "Idle",
+		1: "MakingDough",
+		2: "Sleeping",
+	}
+	State_value = map[string]int32{
+		"Idle":        0,
+		"MakingDough": 1,
+		"Sleeping":    2,
+	}
+)
+
+func (x State) Enum() *State {
+	p := new(State)
+	*p = x
+	return p
+}
+
+func (x State) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (State) Descriptor() protoreflect.EnumDescriptor {
+	return file_panettiere_v1_service_proto_enumTypes[2].Descriptor()
+}
+
+func (State) Type() protoreflect.EnumType {
+	return &file_panettiere_v1_service_proto_enumTypes[2]
+}
+
+func (x State) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use State.Descriptor instead.
+func (State) EnumDescriptor() ([]byte, []int) {
+	return file_panettiere_v1_service_proto_rawDescGZIP(), []int{2}
+}
+
 type DoughRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	OrderId       string                 `protobuf:"bytes,1,opt,name=OrderId,proto3" json:"OrderId,omitempty"`
@@ -271,11 +321,87 @@ func (x *StatusResponse) GetStatus() string {
 	return ""
 }
 
+type WatchStatusResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	State          State                  `protobuf:"varint,1,opt,name=state,proto3,enum=panettiere.v1.State" json:"state,omitempty"`
+	OrderId        string                 `protobuf:"bytes,2,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	Since          *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=since,proto3" json:"since,omitempty"`
+	NextSleep      *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=next_sleep,json=nextSleep,proto3" json:"next_sleep,omitempty"`
+	SleepAfterWork bool                   `protobuf:"varint,5,opt,name=sleep_after_work,json=sleepAfterWork,proto3" json:"sleep_after_work,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *WatchStatusResponse) Reset() {
+	*x = WatchStatusResponse{}
+	mi := &file_panettiere_v1_service_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchStatusResponse) ProtoMessage() {}
+
+func (x *WatchStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_panettiere_v1_service_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchStatusResponse.ProtoReflect.Descriptor instead.
+func (*WatchStatusResponse) Descriptor() ([]byte, []int) {
+	return file_panettiere_v1_service_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *WatchStatusResponse) GetState() State {
+	if x != nil {
+		return x.State
+	}
+	return State_Idle
+}
+
+func (x *WatchStatusResponse) GetOrderId() string {
+	if x != nil {
+		return x.OrderId
+	}
+	return ""
+}
+
+func (x *WatchStatusResponse) GetSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Since
+	}
+	return nil
+}
+
+func (x *WatchStatusResponse) GetNextSleep() *timestamppb.Timestamp {
+	if x != nil {
+		return x.NextSleep
+	}
+	return nil
+}
+
+func (x *WatchStatusResponse) GetSleepAfterWork() bool {
+	if x != nil {
+		return x.SleepAfterWork
+	}
+	return false
+}
+
 var File_panettiere_v1_service_proto protoreflect.FileDescriptor
 
 const file_panettiere_v1_service_proto_rawDesc = "" +
 	"\n" +
-	"\x1bpanettiere/v1/service.proto\x12\rpanettiere.v1\x1a\x1bgoogle/protobuf/empty.proto\"\x89\x01\n" +
+	"\x1bpanettiere/v1/service.proto\x12\rpanettiere.v1\x1a\x1bgoogle/protobuf/empty.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\x89\x01\n" +
 	"\fDoughRequest\x12\x18\n" +
 	"\aOrderId\x18\x01 \x01(\tR\aOrderId\x121\n" +
 	"\x06border\x18\x02 \x01(\x0e2\x19.panettiere.v1.BorderKindR\x06border\x12,\n" +
@@ -283,7 +409,14 @@ const file_panettiere_v1_service_proto_rawDesc = "" +
 	"\rDoughResponse\x12\x18\n" +
 	"\acontent\x18\x01 \x01(\tR\acontent\"(\n" +
 	"\x0eStatusResponse\x12\x16\n" +
-	"\x06status\x18\x01 \x01(\tR\x06status*G\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\"\xf3\x01\n" +
+	"\x13WatchStatusResponse\x12*\n" +
+	"\x05state\x18\x01 \x01(\x0e2\x14.panettiere.v1.StateR\x05state\x12\x19\n" +
+	"\border_id\x18\x02 \x01(\tR\aorderId\x120\n" +
+	"\x05since\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x05since\x129\n" +
+	"\n" +
+	"next_sleep\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tnextSleep\x12(\n" +
+	"\x10sleep_after_work\x18\x05 \x01(\bR\x0esleepAfterWork*G\n" +
 	"\n" +
 	"BorderKind\x12\f\n" +
 	"\bNoBorder\x10\x00\x12\x0f\n" +
@@ -294,10 +427,15 @@ const file_panettiere_v1_service_proto_rawDesc = "" +
 	"\x05Small\x10\x00\x12\n" +
 	"\n" +
 	"\x06Medium\x10\x01\x12\t\n" +
-	"\x05Large\x10\x022\xa0\x01\n" +
+	"\x05Large\x10\x02*0\n" +
+	"\x05State\x12\b\n" +
+	"\x04Idle\x10\x00\x12\x0f\n" +
+	"\vMakingDough\x10\x01\x12\f\n" +
+	"\bSleeping\x10\x022\xef\x01\n" +
 	"\x11PanettiereService\x12H\n" +
 	"\tMakeDough\x12\x1b.panettiere.v1.DoughRequest\x1a\x1c.panettiere.v1.DoughResponse\"\x00\x12A\n" +
-	"\x06Status\x12\x16.google.protobuf.Empty\x1a\x1d.panettiere.v1.StatusResponse\"\x00B.Z,github.com/taldoflemis/box-box/panettiere/v1b\x06proto3"
+	"\x06Status\x12\x16.google.protobuf.Empty\x1a\x1d.panettiere.v1.StatusResponse\"\x00\x12M\n" +
+	"\vWatchStatus\x12\x16.google.protobuf.Empty\x1a\".panettiere.v1.WatchStatusResponse\"\x000\x01B.Z,github.com/taldoflemis/box-box/panettiere/v1b\x06proto3"
 
 var (
 	file_panettiere_v1_service_proto_rawDescOnce sync.Once
@@ -311,28 +449,36 @@ func file_panettiere_v1_service_proto_rawDescGZIP() []byte {
 	return file_panettiere_v1_service_proto_rawDescData
 }
 
-var file_panettiere_v1_service_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
-var file_panettiere_v1_service_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_panettiere_v1_service_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_panettiere_v1_service_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
 var file_panettiere_v1_service_proto_goTypes = []any{
-	(BorderKind)(0),        // 0: panettiere.v1.BorderKind
-	(PizzaSize)(0),         // 1: panettiere.v1.PizzaSize
-	(*DoughRequest)(nil),   // 2: panettiere.v1.DoughRequest
-	(*DoughResponse)(nil),  // 3: panettiere.v1.DoughResponse
-	(*StatusResponse)(nil), // 4: panettiere.v1.StatusResponse
-	(*emptypb.Empty)(nil),  // 5: google.protobuf.Empty
+	(BorderKind)(0),               // 0: panettiere.v1.BorderKind
+	(PizzaSize)(0),                // 1: panettiere.v1.PizzaSize
+	(State)(0),                    // 2: panettiere.v1.State
+	(*DoughRequest)(nil),          // 3: panettiere.v1.DoughRequest
+	(*DoughResponse)(nil),         // 4: panettiere.v1.DoughResponse
+	(*StatusResponse)(nil),        // 5: panettiere.v1.StatusResponse
+	(*WatchStatusResponse)(nil),   // 6: panettiere.v1.WatchStatusResponse
+	(*timestamppb.Timestamp)(nil), // 7: google.protobuf.Timestamp
+	(*emptypb.Empty)(nil),         // 8: google.protobuf.Empty
 }
 var file_panettiere_v1_service_proto_depIdxs = []int32{
 	0, // 0: panettiere.v1.DoughRequest.border:type_name -> panettiere.v1.BorderKind
 	1, // 1: panettiere.v1.DoughRequest.size:type_name -> panettiere.v1.PizzaSize
-	2, // 2: panettiere.v1.PanettiereService.MakeDough:input_type -> panettiere.v1.DoughRequest
-	5, // 3: panettiere.v1.PanettiereService.Status:input_type -> google.protobuf.Empty
-	3, // 4: panettiere.v1.PanettiereService.MakeDough:output_type -> panettiere.v1.DoughResponse
-	4, // 5: panettiere.v1.PanettiereService.Status:output_type -> panettiere.v1.StatusResponse
-	4, // [4:6] is the sub-list for method output_type
-	2, // [2:4] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	2, // 2: panettiere.v1.WatchStatusResponse.state:type_name -> panettiere.v1.State
+	7, // 3: panettiere.v1.WatchStatusResponse.since:type_name -> google.protobuf.Timestamp
+	7, // 4: panettiere.v1.WatchStatusResponse.next_sleep:type_name -> google.protobuf.Timestamp
+	3, // 5: panettiere.v1.PanettiereService.MakeDough:input_type -> panettiere.v1.DoughRequest
+	8, // 6: panettiere.v1.PanettiereService.Status:input_type -> google.protobuf.Empty
+	8, // 7: panettiere.v1.PanettiereService.WatchStatus:input_type -> google.protobuf.Empty
+	4, // 8: panettiere.v1.PanettiereService.MakeDough:output_type -> panettiere.v1.DoughResponse
+	5, // 9: panettiere.v1.PanettiereService.Status:output_type -> panettiere.v1.StatusResponse
+	6, // 10: panettiere.v1.PanettiereService.WatchStatus:output_type -> panettiere.v1.WatchStatusResponse
+	8, // [8:11] is the sub-list for method output_type
+	5, // [5:8] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	5, // [5:5] is the sub-list for extension extendee
+	0, // [0:5] is the sub-list for field type_name
 }
 
 func init() { file_panettiere_v1_service_proto_init() }
@@ -345,8 +491,8 @@ func file_panettiere_v1_service_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_panettiere_v1_service_proto_rawDesc), len(file_panettiere_v1_service_proto_rawDesc)),
-			NumEnums:      2,
-			NumMessages:   3,
+			NumEnums:      3,
+			NumMessages:   4,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -20,8 +20,9 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	PanettiereService_MakeDough_FullMethodName = "/panettiere.v1.PanettiereService/MakeDough"
-	PanettiereService_Status_FullMethodName    = "/panettiere.v1.PanettiereService/Status"
+	PanettiereService_MakeDough_FullMethodName   = "/panettiere.v1.PanettiereService/MakeDough"
+	PanettiereService_Status_FullMethodName      = "/panettiere.v1.PanettiereService/Status"
+	PanettiereService_WatchStatus_FullMethodName = "/panettiere.v1.PanettiereService/WatchStatus"
 )
 
 // PanettiereServiceClient is the client API for PanettiereService service.
@@ -30,6 +31,7 @@ const (
 type PanettiereServiceClient interface {
 	MakeDough(ctx context.Context, in *DoughRequest, opts ...grpc.CallOption) (*DoughResponse, error)
 	Status(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*StatusResponse, error)
+	WatchStatus(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchStatusResponse], error)
 }
 
 type panettiereServiceClient struct {
@@ -60,12 +62,32 @@ func (c *panettiereServiceClient) Status(ctx context.Context, in *emptypb.Empty,
 	return out, nil
 }
 
+func (c *panettiereServiceClient) WatchStatus(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchStatusResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PanettiereService_ServiceDesc.Streams[0], PanettiereService_WatchStatus_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[emptypb.Empty, WatchStatusResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PanettiereService_WatchStatusClient = grpc.ServerStreamingClient[WatchStatusResponse]
+
 // PanettiereServiceServer is the server API for PanettiereService service.
 // All implementations must embed UnimplementedPanettiereServiceServer
 // for forward compatibility.
 type PanettiereServiceServer interface {
 	MakeDough(context.Context, *DoughRequest) (*DoughResponse, error)
 	Status(context.Context, *emptypb.Empty) (*StatusResponse, error)
+	WatchStatus(*emptypb.Empty, grpc.ServerStreamingServer[WatchStatusResponse]) error
 	mustEmbedUnimplementedPanettiereServiceServer()
 }
 
@@ -82,6 +104,9 @@ func (UnimplementedPanettiereServiceServer) MakeDough(context.Context, *DoughReq
 func (UnimplementedPanettiereServiceServer) Status(context.Context, *emptypb.Empty) (*StatusResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Status not implemented")
 }
+func (UnimplementedPanettiereServiceServer) WatchStatus(*emptypb.Empty, grpc.ServerStreamingServer[WatchStatusResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchStatus not implemented")
+}
 func (UnimplementedPanettiereServiceServer) mustEmbedUnimplementedPanettiereServiceServer() {}
 func (UnimplementedPanettiereServiceServer) testEmbeddedByValue()                           {}
 
@@ -139,6 +164,17 @@ func _PanettiereService_Status_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _PanettiereService_WatchStatus_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(emptypb.Empty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PanettiereServiceServer).WatchStatus(m, &grpc.GenericServerStream[emptypb.Empty, WatchStatusResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PanettiereService_WatchStatusServer = grpc.ServerStreamingServer[WatchStatusResponse]
+
 // PanettiereService_ServiceDesc is the grpc.ServiceDesc for PanettiereService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -155,6 +191,12 @@ var PanettiereService_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _PanettiereService_Status_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchStatus",
+			Handler:       _PanettiereService_WatchStatus_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "panettiere/v1/service.proto",
 }
@@ -33,7 +33,10 @@ function makeAPizzaRequest() {
   const orderEndpoint = `${paddock}/v1/order`;
   const headers = { "Content-Type": "application/json" };
   const body = JSON.stringify({
-    size: "large",
+    items: [
+      { size: "large", border: "cheddar", toppings: ["pepperoni"], quantity: 2 },
+      { size: "small", toppings: ["basil"] },
+    ],
     username: "tubias",
     destination: "manoel moreira",
   });